@@ -0,0 +1,96 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package action is the api client for the Action facade, used by the
+// "juju run-action" client path to enqueue a predefined machine action
+// (see state.RegisterMachineAction) against a receiver and retrieve its
+// result once the action has run.
+package action
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/rpc/params"
+)
+
+// Client is the api client for the Action facade.
+type Client struct {
+	facade base.FacadeCaller
+}
+
+// NewClient creates an action api client.
+func NewClient(caller base.APICaller) *Client {
+	facadeCaller := base.NewFacadeCaller(caller, "Action")
+	return &Client{facade: facadeCaller}
+}
+
+// EnqueueUnit queues name against receiverTag (a machine or unit tag)
+// with the given parameters, and returns the id of the resulting
+// state.Action so the caller can poll for its result. receiverTag,
+// name, and parameters are validated controller-side against the
+// registry state.ValidateMachineAction consults; an unknown name or a
+// payload that fails its JSON schema comes back as an *params.Error in
+// this receiver's result rather than as an error from the Enqueue call
+// itself, and EnqueueUnit returns its message as a plain error.
+func (c *Client) EnqueueUnit(receiverTag, name string, parameters map[string]interface{}) (string, error) {
+	arg := params.Actions{
+		Actions: []params.Action{{
+			Receiver:   receiverTag,
+			Name:       name,
+			Parameters: parameters,
+		}},
+	}
+	var results params.ActionResults
+	if err := c.facade.FacadeCall("Enqueue", arg, &results); err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(results.Results) != 1 {
+		return "", errors.Errorf("expected 1 result, got %d", len(results.Results))
+	}
+	result := results.Results[0]
+	if result.Error != nil {
+		return "", errors.New(result.Error.Message)
+	}
+	return result.Action.Tag, nil
+}
+
+// Action is the state of a single queued or completed action, as
+// reported by the controller.
+type Action struct {
+	ID      string
+	Status  string
+	Message string
+	Output  map[string]interface{}
+}
+
+// Actions returns the current state of each action in ids, in the same
+// order, for a caller polling the outcome of a prior EnqueueUnit call.
+func (c *Client) Actions(ids []string) ([]Action, error) {
+	tags := make([]params.Entity, len(ids))
+	for i, id := range ids {
+		tags[i] = params.Entity{Tag: id}
+	}
+	var results params.ActionResults
+	if err := c.facade.FacadeCall("Actions", params.Entities{Entities: tags}, &results); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(results.Results) != len(ids) {
+		return nil, errors.Errorf("expected %d results, got %d", len(ids), len(results.Results))
+	}
+
+	out := make([]Action, len(results.Results))
+	for i, result := range results.Results {
+		if result.Error != nil {
+			out[i] = Action{ID: ids[i], Status: "error", Message: result.Error.Message}
+			continue
+		}
+		out[i] = Action{
+			ID:      result.Action.Tag,
+			Status:  result.Status,
+			Message: result.Message,
+			Output:  result.Output,
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package action_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base/testing"
+	apiaction "github.com/juju/juju/api/client/action"
+	"github.com/juju/juju/rpc/params"
+	coretesting "github.com/juju/juju/testing"
+)
+
+var _ = gc.Suite(&ActionSuite{})
+
+type ActionSuite struct {
+	coretesting.BaseSuite
+}
+
+func (s *ActionSuite) TestNewClient(c *gc.C) {
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		return nil
+	})
+	client := apiaction.NewClient(apiCaller)
+	c.Assert(client, gc.NotNil)
+}
+
+func (s *ActionSuite) TestEnqueueUnit(c *gc.C) {
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(objType, gc.Equals, "Action")
+		c.Check(request, gc.Equals, "Enqueue")
+		c.Check(arg, gc.DeepEquals, params.Actions{
+			Actions: []params.Action{{
+				Receiver:   "machine-0",
+				Name:       "reboot",
+				Parameters: map[string]interface{}{"now": true},
+			}},
+		})
+		*(result.(*params.ActionResults)) = params.ActionResults{
+			Results: []params.ActionResult{{
+				Action: &params.Action{Tag: "action-2"},
+			}},
+		}
+		return nil
+	})
+	client := apiaction.NewClient(apiCaller)
+	id, err := client.EnqueueUnit("machine-0", "reboot", map[string]interface{}{"now": true})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(id, gc.Equals, "action-2")
+}
+
+func (s *ActionSuite) TestEnqueueUnitRejected(c *gc.C) {
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ActionResults)) = params.ActionResults{
+			Results: []params.ActionResult{{
+				Error: &params.Error{Message: `validation failed: (root) : "host" property is missing and required, given {}`},
+			}},
+		}
+		return nil
+	})
+	client := apiaction.NewClient(apiCaller)
+	_, err := client.EnqueueUnit("machine-0", "ping-host", map[string]interface{}{})
+	c.Assert(err, gc.ErrorMatches, `validation failed.*"host" property is missing.*`)
+}
+
+func (s *ActionSuite) TestActions(c *gc.C) {
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(request, gc.Equals, "Actions")
+		c.Check(arg, gc.DeepEquals, params.Entities{Entities: []params.Entity{{Tag: "action-2"}}})
+		*(result.(*params.ActionResults)) = params.ActionResults{
+			Results: []params.ActionResult{{
+				Action:  &params.Action{Tag: "action-2"},
+				Status:  "completed",
+				Message: "done",
+				Output:  map[string]interface{}{"rc": 0},
+			}},
+		}
+		return nil
+	})
+	client := apiaction.NewClient(apiCaller)
+	results, err := client.Actions([]string{"action-2"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, jc.DeepEquals, []apiaction.Action{{
+		ID:      "action-2",
+		Status:  "completed",
+		Message: "done",
+		Output:  map[string]interface{}{"rc": 0},
+	}})
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ErrSecretCorrupt indicates a secret's payload failed checksum
+// verification: the bytes the client received do not match the digest
+// the controller recorded when the revision was created. This matters
+// most once a secret is backed by an external provider (Vault, AWS SSM,
+// GCP Secret Manager, Azure Key Vault), since the payload then traverses
+// a system outside the controller's trust boundary on its way to the
+// client.
+type ErrSecretCorrupt struct {
+	URI string
+}
+
+// Error is part of the error interface.
+func (e *ErrSecretCorrupt) Error() string {
+	return fmt.Sprintf("secret %q failed checksum verification", e.URI)
+}
+
+// IsErrSecretCorrupt reports whether err is an *ErrSecretCorrupt.
+func IsErrSecretCorrupt(err error) bool {
+	_, ok := errors.Cause(err).(*ErrSecretCorrupt)
+	return ok
+}
+
+// checksum computes the SHA-256 hex digest of data's canonical
+// encoding: keys sorted, joined as "key=value\n" with '\\', '=' and '\n'
+// escaped in both key and value first, so that distinct data maps never
+// serialize to the same line (e.g. without escaping, {"a=b": "c"} and
+// {"a": "b=c"} would both produce the line "a=b=c\n"). This must match
+// exactly how the controller computes SecretValueResult.Checksum and
+// SecretMetadata.Checksum when a revision is created.
+func checksum(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(escapeChecksumField(k))
+		b.WriteByte('=')
+		b.WriteString(escapeChecksumField(data[k]))
+		b.WriteByte('\n')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// escapeChecksumField escapes backslash, '=' and '\n' in s so it can be
+// used as a key or value in checksum's canonical "key=value\n" encoding
+// without colliding with the field or line separators.
+func escapeChecksumField(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`)
+	return r.Replace(s)
+}
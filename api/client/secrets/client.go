@@ -0,0 +1,216 @@
+// Copyright 2021 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package secrets
+
+import (
+	"context"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/rpc/params"
+	secretprovider "github.com/juju/juju/secrets/provider"
+)
+
+// Client is the api client for the Secrets facade.
+type Client struct {
+	facade base.FacadeCaller
+}
+
+// NewClient creates a secrets api client.
+func NewClient(caller base.APICaller) *Client {
+	facadeCaller := base.NewFacadeCaller(caller, "Secrets")
+	return &Client{facade: facadeCaller}
+}
+
+// SecretDetails holds a secret's metadata and, when requested, its
+// value.
+type SecretDetails struct {
+	Metadata secrets.SecretMetadata
+	Value    secrets.SecretValue
+	Error    string
+}
+
+// SecretFilter narrows a ListSecretsPage query to secrets matching all
+// of its non-zero fields.
+type SecretFilter struct {
+	// OwnerTag, if set, matches secrets owned by this entity.
+	OwnerTag string
+
+	// ScopeTag, if set, matches secrets scoped to this entity.
+	ScopeTag string
+
+	// Label, if set, is a glob pattern matched against the secret
+	// label.
+	Label string
+
+	// URIs, if set, restricts the result to these secrets.
+	URIs []string
+
+	// Provider, if set, matches secrets backed by this external
+	// provider.
+	Provider string
+
+	// CreatedAfter and CreatedBefore, if set, bound the secret's
+	// creation time.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+}
+
+// ListSecretsArgs controls a paged, filtered ListSecretsPage call.
+type ListSecretsArgs struct {
+	// Filter restricts which secrets are returned.
+	Filter SecretFilter
+
+	// PageToken resumes a previous call. An empty token starts from the
+	// beginning of the result set.
+	PageToken string
+
+	// PageSize caps the number of secrets returned in this page. The
+	// controller may return fewer, but never more.
+	PageSize int
+
+	// ShowSecrets requests that each secret in the page also have its
+	// value resolved.
+	ShowSecrets bool
+}
+
+// SecretsPage is one page of a ListSecretsPage result.
+type SecretsPage struct {
+	// Secrets is this page's worth of matching secrets.
+	Secrets []SecretDetails
+
+	// NextPageToken is opaque to the caller. An empty value means there
+	// are no further pages.
+	NextPageToken string
+}
+
+// ListSecretsPage lists one page of secrets matching args. Filtering is
+// pushed down to the controller's state query, and only the secrets in
+// the returned page have their values resolved when ShowSecrets is set
+// - this keeps a ShowSecrets fetch against an external provider to one
+// network call per secret actually returned, rather than per secret in
+// the model.
+func (c *Client) ListSecretsPage(args ListSecretsArgs) (SecretsPage, error) {
+	arg := params.ListSecretsArgs{
+		Filter: params.SecretFilter{
+			OwnerTag:      args.Filter.OwnerTag,
+			ScopeTag:      args.Filter.ScopeTag,
+			Label:         args.Filter.Label,
+			URIs:          args.Filter.URIs,
+			Provider:      args.Filter.Provider,
+			CreatedAfter:  args.Filter.CreatedAfter,
+			CreatedBefore: args.Filter.CreatedBefore,
+		},
+		PageToken:   args.PageToken,
+		PageSize:    args.PageSize,
+		ShowSecrets: args.ShowSecrets,
+	}
+	var results params.ListSecretResults
+	if err := c.facade.FacadeCall("ListSecrets", arg, &results); err != nil {
+		return SecretsPage{}, errors.Trace(err)
+	}
+
+	info := make([]SecretDetails, len(results.Results))
+	for i, r := range results.Results {
+		uri, err := secrets.ParseURI(r.URI)
+		if err != nil {
+			return SecretsPage{}, errors.Trace(err)
+		}
+		info[i] = SecretDetails{
+			Metadata: secrets.SecretMetadata{
+				URI:            uri,
+				Version:        r.Version,
+				OwnerTag:       r.OwnerTag,
+				ScopeTag:       r.ScopeTag,
+				Provider:       r.Provider,
+				ProviderID:     r.ProviderID,
+				RotatePolicy:   secrets.RotatePolicy(r.RotatePolicy),
+				ExpireTime:     r.ExpireTime,
+				NextRotateTime: r.NextRotateTime,
+				Description:    r.Description,
+				Label:          r.Label,
+				Revision:       r.Revision,
+				CreateTime:     r.CreateTime,
+				UpdateTime:     r.UpdateTime,
+				Checksum:       r.Checksum,
+			},
+		}
+		if !args.ShowSecrets {
+			continue
+		}
+		value, valueErr := c.resolveValue(r)
+		if valueErr != nil {
+			info[i].Error = valueErr.Error()
+			continue
+		}
+		info[i].Value = value
+	}
+	return SecretsPage{Secrets: info, NextPageToken: results.NextPageToken}, nil
+}
+
+// ListSecrets lists every secret in the model, decrypting each one if
+// showSecrets is true. It is a convenience wrapper around
+// ListSecretsPage that pages through the entire result set; callers that
+// can filter, or that care about scaling to a model with many secrets,
+// should call ListSecretsPage directly instead.
+func (c *Client) ListSecrets(showSecrets bool) ([]SecretDetails, error) {
+	var all []SecretDetails
+	args := ListSecretsArgs{ShowSecrets: showSecrets}
+	for {
+		page, err := c.ListSecretsPage(args)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		all = append(all, page.Secrets...)
+		if page.NextPageToken == "" {
+			return all, nil
+		}
+		args.PageToken = page.NextPageToken
+	}
+}
+
+// resolveValue returns the decrypted value for a single ListSecrets
+// result, inlined by the controller for the built-in provider or
+// fetched from the external provider named in r.Provider otherwise.
+func (c *Client) resolveValue(r params.ListSecretResult) (secrets.SecretValue, error) {
+	if r.Value != nil {
+		if r.Value.Error != nil {
+			return nil, errors.New(r.Value.Error.Message)
+		}
+		if r.Value.Checksum != "" && checksum(r.Value.Data) != r.Value.Checksum {
+			return nil, &ErrSecretCorrupt{URI: r.URI}
+		}
+		return secrets.NewSecretValue(r.Value.Data), nil
+	}
+	if r.Provider == "" || r.Provider == secretprovider.Juju {
+		return nil, errors.Errorf("secret %q has no inlined value", r.URI)
+	}
+
+	// r.ProviderConfig is the backend config (vault endpoint, SSM region,
+	// GCP project ID, Azure Key Vault URL, ...) the controller resolved
+	// from model config when building this result; every backend's
+	// NewProvider rejects an empty config for its own required attribute,
+	// so passing nil here would fail on every external-provider secret.
+	p, err := secretprovider.NewProvider(r.Provider, r.ProviderConfig)
+	if err != nil {
+		return nil, errors.Annotatef(err, "looking up %q secret provider", r.Provider)
+	}
+	value, err := p.AccessSecret(context.Background(), r.ProviderID)
+	if err != nil {
+		return nil, errors.Annotatef(err, "fetching secret %q from %q", r.URI, r.Provider)
+	}
+	if r.Checksum != "" {
+		data, err := value.EncodedValues()
+		if err != nil {
+			return nil, errors.Annotatef(err, "reading secret %q fetched from %q", r.URI, r.Provider)
+		}
+		if checksum(data) != r.Checksum {
+			return nil, &ErrSecretCorrupt{URI: r.URI}
+		}
+	}
+	return value, nil
+}
@@ -4,8 +4,14 @@
 package secrets_test
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
@@ -13,9 +19,60 @@ import (
 	apisecrets "github.com/juju/juju/api/client/secrets"
 	"github.com/juju/juju/core/secrets"
 	"github.com/juju/juju/rpc/params"
+	secretprovider "github.com/juju/juju/secrets/provider"
 	coretesting "github.com/juju/juju/testing"
 )
 
+// fakeProviderID is the id registered for fakeExternalProvider, which
+// stands in for a real external backend (Vault, SSM, ...) so these
+// tests can exercise ListSecrets' external-provider path without a live
+// service.
+const fakeProviderID = "fake-test-provider"
+
+func init() {
+	secretprovider.RegisterProvider(fakeProviderID, func(map[string]interface{}) (secretprovider.Provider, error) {
+		return &fakeExternalProvider{}, nil
+	})
+}
+
+// fakeExternalProvider always returns the same fixed value from
+// AccessSecret, regardless of providerID.
+type fakeExternalProvider struct{}
+
+func (*fakeExternalProvider) StoreSecret(context.Context, *secrets.URI, secrets.SecretValue) (string, error) {
+	return "", errors.NotImplementedf("StoreSecret")
+}
+
+func (*fakeExternalProvider) AccessSecret(context.Context, string) (secrets.SecretValue, error) {
+	return secrets.NewSecretValue(map[string]string{"foo": "bar"}), nil
+}
+
+func (*fakeExternalProvider) DeleteSecret(context.Context, string) error {
+	return errors.NotImplementedf("DeleteSecret")
+}
+
+// canonicalChecksum independently reproduces checksum.go's canonical
+// encoding (sorted "key=value\n" lines, '\\'/'='/'\n' escaped), so these
+// tests don't need access to its unexported checksum function to
+// construct a valid checksum for test fixtures.
+func canonicalChecksum(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(escape.Replace(k))
+		b.WriteByte('=')
+		b.WriteString(escape.Replace(data[k]))
+		b.WriteByte('\n')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
 var _ = gc.Suite(&SecretsSuite{})
 
 type SecretsSuite struct {
@@ -92,6 +149,71 @@ func (s *SecretsSuite) TestListSecrets(c *gc.C) {
 	}})
 }
 
+func (s *SecretsSuite) TestListSecretsCorruptPayload(c *gc.C) {
+	data := map[string]string{"foo": "bar"}
+	uri := secrets.NewURI()
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ListSecretResults)) = params.ListSecretResults{
+			[]params.ListSecretResult{{
+				URI: uri.String(),
+				Value: &params.SecretValueResult{
+					Data:     data,
+					Checksum: "not-the-right-checksum",
+				},
+			}},
+		}
+		return nil
+	})
+	client := apisecrets.NewClient(apiCaller)
+	result, err := client.ListSecrets(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 1)
+	c.Assert(result[0].Error, gc.Equals, (&apisecrets.ErrSecretCorrupt{URI: uri.String()}).Error())
+	c.Assert(result[0].Value, gc.IsNil)
+}
+
+func (s *SecretsSuite) TestListSecretsExternalProvider(c *gc.C) {
+	uri := secrets.NewURI()
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ListSecretResults)) = params.ListSecretResults{
+			[]params.ListSecretResult{{
+				URI:        uri.String(),
+				Provider:   fakeProviderID,
+				ProviderID: "provider-secret-id",
+				Checksum:   canonicalChecksum(map[string]string{"foo": "bar"}),
+			}},
+		}
+		return nil
+	})
+	client := apisecrets.NewClient(apiCaller)
+	result, err := client.ListSecrets(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 1)
+	c.Assert(result[0].Error, gc.Equals, "")
+	c.Assert(result[0].Value, jc.DeepEquals, secrets.NewSecretValue(map[string]string{"foo": "bar"}))
+}
+
+func (s *SecretsSuite) TestListSecretsExternalProviderCorruptPayload(c *gc.C) {
+	uri := secrets.NewURI()
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.ListSecretResults)) = params.ListSecretResults{
+			[]params.ListSecretResult{{
+				URI:        uri.String(),
+				Provider:   fakeProviderID,
+				ProviderID: "provider-secret-id",
+				Checksum:   "not-the-right-checksum",
+			}},
+		}
+		return nil
+	})
+	client := apisecrets.NewClient(apiCaller)
+	result, err := client.ListSecrets(true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 1)
+	c.Assert(result[0].Error, gc.Equals, (&apisecrets.ErrSecretCorrupt{URI: uri.String()}).Error())
+	c.Assert(result[0].Value, gc.IsNil)
+}
+
 func (s *SecretsSuite) TestListSecretsError(c *gc.C) {
 	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
 		*(result.(*params.ListSecretResults)) = params.ListSecretResults{
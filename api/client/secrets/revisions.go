@@ -0,0 +1,91 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package secrets
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/rpc/params"
+)
+
+// SecretRevision describes one historical version of a secret's value,
+// mirroring the immutable versioned payloads exposed by external
+// providers such as GCP Secret Manager, AWS SSM and Azure Key Vault.
+type SecretRevision struct {
+	// Revision is the revision number, starting at 1.
+	Revision int
+
+	// CreateTime is when this revision was created.
+	CreateTime time.Time
+
+	// ExpireTime, if set, is when this revision will be auto-pruned.
+	ExpireTime *time.Time
+
+	// DestroyTime, if set, is when this revision was superseded and is
+	// no longer the latest.
+	DestroyTime *time.Time
+
+	// ProviderID is the opaque id this revision's value is stored under
+	// in the secret's backing provider.
+	ProviderID string
+
+	// Checksum is the SHA-256 hex digest of the revision's canonical
+	// data map, allowing callers to detect tampering after the fact.
+	Checksum string
+
+	// Value holds the decrypted payload, populated only when the
+	// controller included one in the result; GetSecretRevisions has no
+	// option to request it.
+	Value secrets.SecretValue
+}
+
+// GetSecretRevisions returns the revision history for uri, oldest
+// first.
+func (c *Client) GetSecretRevisions(uri *secrets.URI) ([]SecretRevision, error) {
+	arg := params.GetSecretRevisionsArg{URI: uri.String()}
+	var result params.SecretRevisionsResult
+	if err := c.facade.FacadeCall("GetSecretRevisions", arg, &result); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if result.Error != nil {
+		return nil, errors.Trace(result.Error)
+	}
+
+	revisions := make([]SecretRevision, len(result.Revisions))
+	for i, r := range result.Revisions {
+		rev := SecretRevision{
+			Revision:    r.Revision,
+			CreateTime:  r.CreateTime,
+			ExpireTime:  r.ExpireTime,
+			DestroyTime: r.DestroyTime,
+			ProviderID:  r.ProviderID,
+			Checksum:    r.Checksum,
+		}
+		if r.Value != nil && r.Value.Error == nil {
+			if r.Checksum != "" && checksum(r.Value.Data) != r.Checksum {
+				return nil, errors.Trace(&ErrSecretCorrupt{URI: uri.String()})
+			}
+			rev.Value = secrets.NewSecretValue(r.Value.Data)
+		}
+		revisions[i] = rev
+	}
+	return revisions, nil
+}
+
+// RevertSecret rolls uri back to revision, making that revision's value
+// the latest one rather than deleting anything after it.
+func (c *Client) RevertSecret(uri *secrets.URI, revision int) error {
+	arg := params.RevertSecretArg{URI: uri.String(), Revision: revision}
+	var result params.ErrorResult
+	if err := c.facade.FacadeCall("RevertSecret", arg, &result); err != nil {
+		return errors.Trace(err)
+	}
+	if result.Error != nil {
+		return errors.Trace(result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package secrets_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/api/base/testing"
+	apisecrets "github.com/juju/juju/api/client/secrets"
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/rpc/params"
+)
+
+func (s *SecretsSuite) TestGetSecretRevisions(c *gc.C) {
+	data := map[string]string{"foo": "bar"}
+	uri := secrets.NewURI()
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		c.Check(request, gc.Equals, "GetSecretRevisions")
+		*(result.(*params.SecretRevisionsResult)) = params.SecretRevisionsResult{
+			Revisions: []params.SecretRevision{{
+				Revision:   1,
+				ProviderID: "provider-id",
+				Checksum:   canonicalChecksum(data),
+				Value:      &params.SecretValueResult{Data: data},
+			}},
+		}
+		return nil
+	})
+	client := apisecrets.NewClient(apiCaller)
+	result, err := client.GetSecretRevisions(uri)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.HasLen, 1)
+	c.Assert(result[0].Value, jc.DeepEquals, secrets.NewSecretValue(data))
+}
+
+func (s *SecretsSuite) TestGetSecretRevisionsCorruptPayload(c *gc.C) {
+	data := map[string]string{"foo": "bar"}
+	uri := secrets.NewURI()
+	apiCaller := testing.APICallerFunc(func(objType string, version int, id, request string, arg, result interface{}) error {
+		*(result.(*params.SecretRevisionsResult)) = params.SecretRevisionsResult{
+			Revisions: []params.SecretRevision{{
+				Revision:   1,
+				ProviderID: "provider-id",
+				Checksum:   "not-the-right-checksum",
+				Value:      &params.SecretValueResult{Data: data},
+			}},
+		}
+		return nil
+	})
+	client := apisecrets.NewClient(apiCaller)
+	_, err := client.GetSecretRevisions(uri)
+	c.Assert(err, gc.ErrorMatches, (&apisecrets.ErrSecretCorrupt{URI: uri.String()}).Error())
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package action holds the "juju run-action" client path for the
+// predefined machine actions registered through
+// state.RegisterMachineAction (see state/actions_registry.go).
+package action
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/juju/names.v2"
+
+	apiaction "github.com/juju/juju/api/client/action"
+	"github.com/juju/juju/cmd/modelcmd"
+)
+
+// RunActionAPI is implemented by apiaction.Client.
+type RunActionAPI interface {
+	EnqueueUnit(receiverTag, name string, parameters map[string]interface{}) (string, error)
+	Close() error
+}
+
+var usageRunActionSummary = `
+Queues a predefined action against a machine.`[1:]
+
+var usageRunActionDetails = `
+Queues name, a predefined machine action registered with
+state.RegisterMachineAction (e.g. "reboot", "collect-metrics",
+"run-script", "gather-debug-info"), against the machine identified by
+--machine. Parameters are passed as key=value pairs and validated
+controller-side against the action's JSON schema before it is queued;
+an invalid payload is rejected with the schema's "validation failed: ..."
+error instead of being queued.
+
+Examples:
+    juju run-action --machine 0 reboot now=true
+    juju run-action --machine 1 collect-metrics plugin=disk-usage
+
+See also:
+    show-action-output
+`
+
+// RunActionCommand queues a predefined machine action.
+type RunActionCommand struct {
+	modelcmd.ModelCommandBase
+
+	machine string
+	action  string
+	params  map[string]interface{}
+
+	apiFunc func() (RunActionAPI, error)
+}
+
+// NewRunActionCommand returns a command that queues a predefined
+// machine action.
+func NewRunActionCommand() cmd.Command {
+	c := &RunActionCommand{}
+	c.apiFunc = func() (RunActionAPI, error) {
+		root, err := c.NewAPIRoot()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return apiaction.NewClient(root), nil
+	}
+	return modelcmd.Wrap(c)
+}
+
+// Info returns help information about the command.
+func (c *RunActionCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "run-action",
+		Args:    "<action name> [<key>=<value> [...]]",
+		Purpose: usageRunActionSummary,
+		Doc:     usageRunActionDetails,
+	}
+}
+
+// SetFlags initializes the flags supported by the command.
+func (c *RunActionCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.ModelCommandBase.SetFlags(f)
+	f.StringVar(&c.machine, "machine", "", "Machine to run the action against")
+}
+
+// Init populates the command with the args from the command line.
+func (c *RunActionCommand) Init(args []string) error {
+	if c.machine == "" {
+		return errors.Errorf("no --machine specified")
+	}
+	if len(args) == 0 {
+		return errors.Errorf("no action name specified")
+	}
+	c.action = args[0]
+
+	params := make(map[string]interface{}, len(args)-1)
+	for _, arg := range args[1:] {
+		key, value, err := splitKeyValue(arg)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		params[key] = value
+	}
+	c.params = params
+	return nil
+}
+
+// splitKeyValue parses a "key=value" command-line argument, interpreting
+// value as a bool if it parses as one so flags like "now=true" reach the
+// controller as the JSON boolean the action's schema expects rather than
+// the literal string "true".
+func splitKeyValue(arg string) (key string, value interface{}, err error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", nil, errors.Errorf("expected key=value, got %q", arg)
+	}
+	switch parts[1] {
+	case "true":
+		return parts[0], true, nil
+	case "false":
+		return parts[0], false, nil
+	}
+	return parts[0], parts[1], nil
+}
+
+// Run is defined on the Command interface.
+func (c *RunActionCommand) Run(ctx *cmd.Context) error {
+	client, err := c.apiFunc()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer client.Close()
+
+	id, err := client.EnqueueUnit(names.NewMachineTag(c.machine).String(), c.action, c.params)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	fmt.Fprintf(ctx.Stdout, "Action queued with id: %s\n", id)
+	return nil
+}
@@ -81,10 +81,29 @@ type AddCAASCommand struct {
 	// Regions are the cloud regions that the nodes of cluster (k8s) are running in.
 	Regions []string
 
+	// storage and workloadStorage override the operator/workload
+	// StorageClass that would otherwise be chosen from the detected
+	// cluster flavor.
+	storage         string
+	workloadStorage string
+
+	// skipStoragePreflight disables cluster flavor/storage detection
+	// entirely, for clusters where probing nodes and StorageClasses
+	// isn't possible or desired.
+	skipStoragePreflight bool
+
 	cloudMetadataStore    CloudMetadataStore
 	fileCredentialStore   jujuclient.CredentialStore
 	apiFunc               func() (AddCloudAPI, error)
 	newClientConfigReader func(string) (clientconfig.ClientConfigFunc, error)
+
+	// listClusterNodes probes the cluster identified by cloudSpec and
+	// credential for its nodes' labels, installed StorageClass names and
+	// API server hostname, for cluster flavor/region detection. The
+	// default implementation only has the endpoint to go on, since a
+	// real client-go based probe isn't available in this checkout; it
+	// never errors, so callers always fall back gracefully.
+	listClusterNodes func(newCloud jujucloud.Cloud, credential jujucloud.Credential) (nodes []NodeInfo, storageClasses []string, apiServerHost string, err error)
 }
 
 // NewAddCAASCommand returns a command to add caas information.
@@ -95,6 +114,9 @@ func NewAddCAASCommand(cloudMetadataStore CloudMetadataStore) cmd.Command {
 		newClientConfigReader: func(caasType string) (clientconfig.ClientConfigFunc, error) {
 			return clientconfig.NewClientConfigReader(caasType)
 		},
+		listClusterNodes: func(newCloud jujucloud.Cloud, _ jujucloud.Credential) ([]NodeInfo, []string, string, error) {
+			return nil, nil, newCloud.Endpoint, nil
+		},
 	}
 	cmd.apiFunc = func() (AddCloudAPI, error) {
 		root, err := cmd.NewAPIRoot()
@@ -121,6 +143,9 @@ func (c *AddCAASCommand) SetFlags(f *gnuflag.FlagSet) {
 	c.CommandBase.SetFlags(f)
 	f.StringVar(&c.clusterName, "cluster-name", "", "Specify the k8s cluster to import")
 	f.Var(regionsFlag{&c.Regions}, "regions", "cluster regions")
+	f.StringVar(&c.storage, "storage", "", "Storage class for the operator storage")
+	f.StringVar(&c.workloadStorage, "workload-storage", "", "Storage class for the workload storage")
+	f.BoolVar(&c.skipStoragePreflight, "skip-storage-preflight", false, "Skip detecting cluster flavor and default storage classes")
 }
 
 // Init populates the command with the args from the command line.
@@ -195,6 +220,10 @@ func (c *AddCAASCommand) Run(ctx *cmd.Context) error {
 		return errors.NotFoundf("clusterName %q", clusterName)
 	}
 	credential := caasConfig.Credentials[context.CredentialName]
+	credential, err = resolveCredential(credential)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	currentCloud := caasConfig.Clouds[context.CloudName]
 
 	cloudCAData, ok := currentCloud.Attributes["CAData"].(string)
@@ -210,6 +239,30 @@ func (c *AddCAASCommand) Run(ctx *cmd.Context) error {
 		CACertificates: []string{cloudCAData},
 	}
 
+	flavor := FlavorGeneric
+	operatorStorage, workloadStorage := c.storage, c.workloadStorage
+	if !c.skipStoragePreflight {
+		nodes, storageClasses, apiServerHost, err := c.listClusterNodes(newCloud, credential)
+		if err != nil {
+			ctx.Infof("Could not detect cluster flavor, defaulting to generic: %v", err)
+		} else {
+			flavor = DetectClusterFlavor(nodes, apiServerHost, storageClasses)
+			logger.Debugf("detected cluster flavor %q", flavor)
+			if len(c.Regions) == 0 {
+				if detected := RegionsFromNodes(nodes); len(detected) > 0 {
+					c.Regions = detected
+				}
+			}
+			defaultOperator, defaultWorkload := defaultStorageClasses(flavor)
+			if operatorStorage == "" {
+				operatorStorage = defaultOperator
+			}
+			if workloadStorage == "" {
+				workloadStorage = defaultWorkload
+			}
+		}
+	}
+
 	regions := c.Regions
 	if regions == nil || len(regions) == 0 {
 		var err error
@@ -221,6 +274,16 @@ func (c *AddCAASCommand) Run(ctx *cmd.Context) error {
 	}
 	newCloud.Regions = buildRegions(regions)
 
+	credAttrs := copyAttrs(credential.Attributes())
+	credAttrs["k8s/flavor"] = string(flavor)
+	if operatorStorage != "" {
+		credAttrs["operator-storage"] = operatorStorage
+	}
+	if workloadStorage != "" {
+		credAttrs["workload-storage"] = workloadStorage
+	}
+	credential = jujucloud.NewCredential(credential.AuthType(), credAttrs)
+
 	if err := addCloudToLocal(c.cloudMetadataStore, newCloud); err != nil {
 		return errors.Trace(err)
 	}
@@ -0,0 +1,220 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package caas
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+
+	jujucloud "github.com/juju/juju/cloud"
+)
+
+// CredentialResolver turns a credential parsed straight out of a
+// kubeconfig context - which may only describe how to obtain a token,
+// not the token itself - into a first-class Juju credential with
+// concrete attribute values. Implementations are looked up by the
+// kubeconfig attributes that identify their provider (an "exec" stanza,
+// an OIDC auth-provider, or a cloud-specific auth-provider name), so
+// add-k8s and update-k8s-credential can resolve credentials the same way
+// without either command hard-coding the provider list.
+type CredentialResolver interface {
+	// Resolve returns cred with any plugin-derived values (a bearer
+	// token, an expiry) filled in or refreshed, by invoking whatever
+	// external mechanism the provider uses.
+	Resolve(cred jujucloud.Credential) (jujucloud.Credential, error)
+}
+
+var (
+	credentialResolversMu sync.Mutex
+	credentialResolvers   = make(map[string]CredentialResolver)
+)
+
+// RegisterCredentialResolver registers a CredentialResolver under name,
+// one of the provider identifiers DetectCredentialResolver recognises
+// from a kubeconfig credential's attributes ("exec", "oidc", "gcp",
+// "aws-iam-authenticator", "azure"). It panics on a duplicate name.
+func RegisterCredentialResolver(name string, resolver CredentialResolver) {
+	credentialResolversMu.Lock()
+	defer credentialResolversMu.Unlock()
+	if _, dup := credentialResolvers[name]; dup {
+		panic("caas: RegisterCredentialResolver called twice for name " + name)
+	}
+	credentialResolvers[name] = resolver
+}
+
+func init() {
+	RegisterCredentialResolver("exec", execCredentialResolver{})
+	RegisterCredentialResolver("oidc", oidcCredentialResolver{})
+	RegisterCredentialResolver("gcp", gcpCredentialResolver{})
+	RegisterCredentialResolver("aws-iam-authenticator", awsCredentialResolver{})
+	RegisterCredentialResolver("azure", azureCredentialResolver{})
+}
+
+// DetectCredentialResolver inspects cred's attributes, as parsed from a
+// kubeconfig context by clientconfig, and returns the CredentialResolver
+// that applies to it. It returns false if cred uses a plain static
+// token or client certificate and needs no resolution.
+func DetectCredentialResolver(cred jujucloud.Credential) (CredentialResolver, bool) {
+	attrs := cred.Attributes()
+
+	name := ""
+	switch {
+	case attrs["exec-command"] != "":
+		name = "exec"
+	case attrs["auth-provider"] == "oidc":
+		name = "oidc"
+	case attrs["auth-provider"] == "gcp":
+		name = "gcp"
+	case attrs["auth-provider"] == "aws-iam-authenticator":
+		name = "aws-iam-authenticator"
+	case attrs["auth-provider"] == "azure":
+		name = "azure"
+	default:
+		return nil, false
+	}
+
+	credentialResolversMu.Lock()
+	defer credentialResolversMu.Unlock()
+	resolver, ok := credentialResolvers[name]
+	return resolver, ok
+}
+
+// resolveCredential resolves cred through DetectCredentialResolver if it
+// needs resolving, otherwise returns it unchanged.
+func resolveCredential(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	resolver, ok := DetectCredentialResolver(cred)
+	if !ok {
+		return cred, nil
+	}
+	resolved, err := resolver.Resolve(cred)
+	if err != nil {
+		return jujucloud.Credential{}, errors.Annotate(err, "resolving credential")
+	}
+	return resolved, nil
+}
+
+// execCredentialResolver resolves credentials described by a kubeconfig
+// "exec" stanza, e.g. the various cloud CLI plugins that print a
+// client.authentication.k8s.io ExecCredential to stdout.
+type execCredentialResolver struct{}
+
+// Resolve is part of the CredentialResolver interface.
+func (execCredentialResolver) Resolve(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	attrs := cred.Attributes()
+	command := attrs["exec-command"]
+	if command == "" {
+		return jujucloud.Credential{}, errors.New("credential has no exec-command attribute")
+	}
+	args := strings.Fields(attrs["exec-args"])
+
+	out, err := exec.Command(command, args...).Output()
+	if err != nil {
+		return jujucloud.Credential{}, errors.Annotatef(err, "invoking exec credential plugin %q", command)
+	}
+	var execCred struct {
+		Status struct {
+			Token string `json:"token"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &execCred); err != nil {
+		return jujucloud.Credential{}, errors.Annotatef(err, "parsing ExecCredential from %q", command)
+	}
+	if execCred.Status.Token == "" {
+		return jujucloud.Credential{}, errors.Errorf("ExecCredential from %q has no status.token", command)
+	}
+
+	newAttrs := copyAttrs(attrs)
+	newAttrs["Token"] = execCred.Status.Token
+	return jujucloud.NewCredential(jujucloud.OAuth2AuthType, newAttrs), nil
+}
+
+// oidcCredentialResolver handles credentials using an OIDC
+// auth-provider. It checks that an id-token is present and passes the
+// credential through unchanged; it does not refresh the id-token against
+// the provider's token endpoint, even when it has expired.
+type oidcCredentialResolver struct{}
+
+// Resolve is part of the CredentialResolver interface.
+func (oidcCredentialResolver) Resolve(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	attrs := cred.Attributes()
+	if attrs["id-token"] == "" {
+		return jujucloud.Credential{}, errors.New("OIDC credential has no id-token to refresh")
+	}
+	// The existing id-token is returned as is; refresh-token and
+	// idp-issuer-url are not used.
+	return cred, nil
+}
+
+// gcpCredentialResolver resolves credentials for GKE clusters configured
+// with the "gcp" auth-provider, by invoking `gcloud config config-helper`
+// to mint an access token.
+type gcpCredentialResolver struct{}
+
+// Resolve is part of the CredentialResolver interface.
+func (gcpCredentialResolver) Resolve(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	out, err := exec.Command("gcloud", "config", "config-helper", "--format=value(credential.access_token)").Output()
+	if err != nil {
+		return jujucloud.Credential{}, errors.Annotate(err, "invoking gcloud to mint an access token")
+	}
+	newAttrs := copyAttrs(cred.Attributes())
+	newAttrs["Token"] = strings.TrimSpace(string(out))
+	return jujucloud.NewCredential(jujucloud.OAuth2AuthType, newAttrs), nil
+}
+
+// awsCredentialResolver resolves credentials for EKS clusters configured
+// with the "aws-iam-authenticator" auth-provider, by invoking
+// `aws-iam-authenticator token -i <cluster-name> --token-only` to mint a
+// short-lived bearer token. It does not invoke `aws eks get-token`.
+type awsCredentialResolver struct{}
+
+// Resolve is part of the CredentialResolver interface.
+func (awsCredentialResolver) Resolve(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	attrs := cred.Attributes()
+	clusterName := attrs["cluster-name"]
+	if clusterName == "" {
+		return jujucloud.Credential{}, errors.New("AWS credential has no cluster-name attribute")
+	}
+	out, err := exec.Command("aws-iam-authenticator", "token", "-i", clusterName, "--token-only").Output()
+	if err != nil {
+		return jujucloud.Credential{}, errors.Annotate(err, "invoking aws-iam-authenticator to mint a token")
+	}
+	newAttrs := copyAttrs(attrs)
+	newAttrs["Token"] = strings.TrimSpace(string(out))
+	return jujucloud.NewCredential(jujucloud.OAuth2AuthType, newAttrs), nil
+}
+
+// azureCredentialResolver resolves credentials for AKS clusters
+// configured with the "azure" auth-provider, by invoking the Azure CLI
+// to mint an access token for the AKS server application.
+type azureCredentialResolver struct{}
+
+// Resolve is part of the CredentialResolver interface.
+func (azureCredentialResolver) Resolve(cred jujucloud.Credential) (jujucloud.Credential, error) {
+	attrs := cred.Attributes()
+	resource := attrs["apiserver-id"]
+	if resource == "" {
+		resource = "6dae42f8-4368-4678-94ff-3960e28e3630" // AKS server application id.
+	}
+	out, err := exec.Command("az", "account", "get-access-token", "--resource", resource, "--query", "accessToken", "-o", "tsv").Output()
+	if err != nil {
+		return jujucloud.Credential{}, errors.Annotate(err, "invoking az to mint an access token")
+	}
+	newAttrs := copyAttrs(attrs)
+	newAttrs["Token"] = strings.TrimSpace(string(out))
+	return jujucloud.NewCredential(jujucloud.OAuth2AuthType, newAttrs), nil
+}
+
+// copyAttrs returns a shallow copy of attrs, so a resolver can add or
+// overwrite keys without mutating the credential it was given.
+func copyAttrs(attrs map[string]string) map[string]string {
+	newAttrs := make(map[string]string, len(attrs)+1)
+	for k, v := range attrs {
+		newAttrs[k] = v
+	}
+	return newAttrs
+}
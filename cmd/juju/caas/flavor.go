@@ -0,0 +1,126 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package caas
+
+import (
+	"sort"
+	"strings"
+)
+
+// ClusterFlavor identifies the distribution of a Kubernetes cluster
+// add-k8s was pointed at, so downstream provisioning can special-case
+// each one (e.g. the operator storage class a managed cluster already
+// provides versus what a local microk8s/kind cluster needs).
+type ClusterFlavor string
+
+const (
+	FlavorGKE       ClusterFlavor = "gke"
+	FlavorEKS       ClusterFlavor = "eks"
+	FlavorAKS       ClusterFlavor = "aks"
+	FlavorOpenShift ClusterFlavor = "openshift"
+	FlavorMicroK8s  ClusterFlavor = "microk8s"
+	FlavorK3s       ClusterFlavor = "k3s"
+	FlavorKind      ClusterFlavor = "kind"
+	FlavorGeneric   ClusterFlavor = "generic"
+)
+
+// NodeInfo is the subset of a k8s Node's metadata DetectClusterFlavor and
+// RegionsFromNodes need, as read off the cluster by a lightweight client
+// using the kubeconfig context's resolved credential.
+type NodeInfo struct {
+	Labels map[string]string
+}
+
+// gkeNodePoolLabel, eksNodeGroupLabel and aksClusterLabel are the node
+// labels each managed offering stamps onto every node, used to detect
+// the cluster's flavor without relying on the API server hostname alone.
+const (
+	gkeNodePoolLabel  = "cloud.google.com/gke-nodepool"
+	eksNodeGroupLabel = "eks.amazonaws.com/nodegroup"
+	aksClusterLabel   = "kubernetes.azure.com/cluster"
+	regionLabel       = "topology.kubernetes.io/region"
+)
+
+// DetectClusterFlavor inspects node labels, the API server's hostname and
+// the cluster's installed StorageClasses to identify which distribution
+// add-k8s is pointed at. It favours node labels - the most specific
+// signal - then the API server hostname, then storage classes, falling
+// back to FlavorGeneric.
+func DetectClusterFlavor(nodes []NodeInfo, apiServerHost string, storageClasses []string) ClusterFlavor {
+	for _, node := range nodes {
+		switch {
+		case node.Labels[gkeNodePoolLabel] != "":
+			return FlavorGKE
+		case node.Labels[eksNodeGroupLabel] != "":
+			return FlavorEKS
+		case node.Labels[aksClusterLabel] != "":
+			return FlavorAKS
+		}
+	}
+
+	host := strings.ToLower(apiServerHost)
+	switch {
+	case strings.Contains(host, "azmk8s.io"):
+		return FlavorAKS
+	case strings.Contains(host, "eks.amazonaws.com"):
+		return FlavorEKS
+	case strings.Contains(host, "container.googleapis.com"):
+		return FlavorGKE
+	case strings.Contains(host, "127.0.0.1") || strings.Contains(host, "localhost"):
+		return FlavorKind
+	}
+
+	for _, sc := range storageClasses {
+		switch {
+		case strings.Contains(sc, "openshift"):
+			return FlavorOpenShift
+		case strings.Contains(sc, "microk8s-hostpath"):
+			return FlavorMicroK8s
+		case strings.Contains(sc, "local-path") && strings.Contains(host, "k3s"):
+			return FlavorK3s
+		}
+	}
+
+	return FlavorGeneric
+}
+
+// RegionsFromNodes collects the distinct topology.kubernetes.io/region
+// labels across nodes, sorted, for use as Regions when the caller did not
+// pass --regions and ListRegions is not supported by the cluster.
+func RegionsFromNodes(nodes []NodeInfo) []string {
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		if region := node.Labels[regionLabel]; region != "" {
+			seen[region] = true
+		}
+	}
+	regions := make([]string, 0, len(seen))
+	for region := range seen {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// defaultStorageClasses returns the operator and workload StorageClass
+// names add-k8s should default to for flavor, absent an explicit
+// --storage/--workload-storage override. Managed offerings already
+// provision a sensible default class; local/dev clusters get the class
+// their own add-on registers.
+func defaultStorageClasses(flavor ClusterFlavor) (operator, workload string) {
+	switch flavor {
+	case FlavorGKE:
+		return "standard", "standard"
+	case FlavorEKS:
+		return "gp2", "gp2"
+	case FlavorAKS:
+		return "default", "default"
+	case FlavorMicroK8s:
+		return "microk8s-hostpath", "microk8s-hostpath"
+	case FlavorK3s:
+		return "local-path", "local-path"
+	default:
+		return "", ""
+	}
+}
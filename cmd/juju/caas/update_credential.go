@@ -0,0 +1,162 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package caas
+
+import (
+	"github.com/juju/cmd"
+	"github.com/juju/errors"
+	"github.com/juju/gnuflag"
+	"gopkg.in/juju/names.v2"
+
+	cloudapi "github.com/juju/juju/api/cloud"
+	"github.com/juju/juju/caas/kubernetes/clientconfig"
+	jujucloud "github.com/juju/juju/cloud"
+	"github.com/juju/juju/cmd/modelcmd"
+	"github.com/juju/juju/jujuclient"
+)
+
+var usageUpdateK8sCredentialSummary = `
+Refreshes a k8s cloud's stored credential from its kubeconfig.`[1:]
+
+var usageUpdateK8sCredentialDetails = `
+Re-invokes the credential resolver (exec plugin, OIDC id-token check, or
+cloud-specific token minting) used when the k8s cloud was added, and
+updates the stored credential with the result. Use this when a credential
+derived from an exec plugin or a cloud CLI's access token has expired.
+
+Examples:
+    juju update-k8s-credential myk8scloud
+    KUBECONFIG=path-to-kubeconfig-file juju update-k8s-credential myk8scloud --cluster-name=my_cluster_name
+
+See also:
+    add-k8s
+`
+
+// UpdateCredentialAPI is implemented by cloudapi.Client, and is the
+// subset UpdateK8sCredentialCommand needs to push a refreshed credential
+// to the controller.
+type UpdateCredentialAPI interface {
+	UpdateCredential(tag string, credential jujucloud.Credential) error
+	Close() error
+}
+
+// UpdateK8sCredentialCommand re-resolves a k8s cloud's credential from
+// its kubeconfig and pushes the refreshed value to the controller.
+type UpdateK8sCredentialCommand struct {
+	modelcmd.ControllerCommandBase
+
+	caasName    string
+	clusterName string
+
+	fileCredentialStore   jujuclient.CredentialStore
+	apiFunc               func() (UpdateCredentialAPI, error)
+	newClientConfigReader func(string) (clientconfig.ClientConfigFunc, error)
+}
+
+// NewUpdateK8sCredentialCommand returns a command that refreshes a k8s
+// cloud's stored credential.
+func NewUpdateK8sCredentialCommand() cmd.Command {
+	c := &UpdateK8sCredentialCommand{
+		fileCredentialStore: jujuclient.NewFileCredentialStore(),
+		newClientConfigReader: func(caasType string) (clientconfig.ClientConfigFunc, error) {
+			return clientconfig.NewClientConfigReader(caasType)
+		},
+	}
+	c.apiFunc = func() (UpdateCredentialAPI, error) {
+		root, err := c.NewAPIRoot()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return cloudapi.NewClient(root), nil
+	}
+	return modelcmd.WrapController(c)
+}
+
+// Info returns help information about the command.
+func (c *UpdateK8sCredentialCommand) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:    "update-k8s-credential",
+		Args:    "<k8s name>",
+		Purpose: usageUpdateK8sCredentialSummary,
+		Doc:     usageUpdateK8sCredentialDetails,
+	}
+}
+
+// SetFlags initializes the flags supported by the command.
+func (c *UpdateK8sCredentialCommand) SetFlags(f *gnuflag.FlagSet) {
+	c.CommandBase.SetFlags(f)
+	f.StringVar(&c.clusterName, "cluster-name", "", "Specify the k8s cluster to import")
+}
+
+// Init populates the command with the args from the command line.
+func (c *UpdateK8sCredentialCommand) Init(args []string) error {
+	if len(args) == 0 {
+		return errors.Errorf("missing k8s name.")
+	}
+	c.caasName = args[0]
+	return cmd.CheckEmpty(args[1:])
+}
+
+// Run is defined on the Command interface.
+func (c *UpdateK8sCredentialCommand) Run(ctx *cmd.Context) error {
+	clientConfigFunc, err := c.newClientConfigReader("kubernetes")
+	if err != nil {
+		return errors.Trace(err)
+	}
+	stdIn, err := getStdinPipe(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	caasConfig, err := clientConfigFunc(stdIn)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var context clientconfig.Context
+	if c.clusterName != "" {
+		for _, ctx := range caasConfig.Contexts {
+			if c.clusterName == ctx.CloudName {
+				context = ctx
+				break
+			}
+		}
+	} else {
+		context = caasConfig.Contexts[caasConfig.CurrentContext]
+	}
+	if (clientconfig.Context{}) == context {
+		return errors.NotFoundf("clusterName %q", c.clusterName)
+	}
+
+	credential, err := resolveCredential(caasConfig.Credentials[context.CredentialName])
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	currentAccountDetails, err := c.CurrentAccountDetails()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	cloudCredTag := names.NewCloudCredentialTag(
+		c.caasName + "/" + currentAccountDetails.User + "/" + context.CredentialName)
+
+	apiClient, err := c.apiFunc()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	defer apiClient.Close()
+
+	if err := apiClient.UpdateCredential(cloudCredTag.String(), credential); err != nil {
+		return errors.Trace(err)
+	}
+
+	newCredentials := &jujucloud.CloudCredential{
+		AuthCredentials: map[string]jujucloud.Credential{context.CredentialName: credential},
+	}
+	if err := c.fileCredentialStore.UpdateCredential(c.caasName, *newCredentials); err != nil {
+		return errors.Trace(err)
+	}
+
+	ctx.Infof("Updated credential %q for cloud %q.", context.CredentialName, c.caasName)
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package database
+
+import (
+	"context"
+
+	"github.com/canonical/sqlair"
+	"github.com/juju/errors"
+)
+
+// DefaultBatchSize is used by BatchTxn when the caller passes a
+// batchSize of 0 or less.
+const DefaultBatchSize = 250
+
+// BatchTxn runs fn once per batchSize-sized slice of items, each inside
+// its own call to db.Txn, so that inserting or updating a large slice of
+// rows does not hold a single transaction open (and its locks) for the
+// entire operation. Go does not allow type parameters on interface
+// methods, so this is a free function taking db as a parameter rather
+// than a TrackedDB method.
+//
+// If fn returns an error for any batch, BatchTxn stops and returns that
+// error wrapped with the index of the first item in the failing batch;
+// batches already committed are not rolled back.
+func BatchTxn[T any](ctx context.Context, db TrackedDB, batchSize int, items []T, fn func(context.Context, *sqlair.TX, []T) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+		err := db.Txn(ctx, func(ctx context.Context, tx *sqlair.TX) error {
+			return fn(ctx, tx, batch)
+		})
+		if err != nil {
+			return errors.Annotatef(err, "running batch starting at item %d", start)
+		}
+	}
+	return nil
+}
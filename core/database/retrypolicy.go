@@ -0,0 +1,69 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package database
+
+import (
+	"strings"
+)
+
+// RetryPolicy decides whether an error returned from a transaction
+// function represents a transient failure that Txn/StdTxn should retry,
+// as opposed to one that should be returned to the caller immediately.
+type RetryPolicy interface {
+	IsRetryable(err error) bool
+}
+
+// RetryPolicyFunc adapts a plain function to a RetryPolicy.
+type RetryPolicyFunc func(err error) bool
+
+// IsRetryable is part of the RetryPolicy interface.
+func (f RetryPolicyFunc) IsRetryable(err error) bool {
+	return f(err)
+}
+
+// sqliteRetryPolicy recognises the transient errors returned by the
+// local SQLite driver under write contention.
+type sqliteRetryPolicy struct{}
+
+// SQLiteRetryPolicy returns the RetryPolicy used for the per-machine
+// SQLite databases: it retries on "database is locked" and "database
+// table is locked", which SQLite returns when another writer holds the
+// single write lock.
+func SQLiteRetryPolicy() RetryPolicy {
+	return sqliteRetryPolicy{}
+}
+
+// IsRetryable is part of the RetryPolicy interface.
+func (sqliteRetryPolicy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked")
+}
+
+// dqliteRetryPolicy recognises the transient errors returned by Dqlite
+// under leadership elections and raft log contention.
+type dqliteRetryPolicy struct{}
+
+// DqliteRetryPolicy returns the RetryPolicy used for the clustered
+// Dqlite controller database: it retries on leadership loss, "no
+// leader", and raft log conflicts, which are expected during an
+// election and resolve once a leader is re-established.
+func DqliteRetryPolicy() RetryPolicy {
+	return dqliteRetryPolicy{}
+}
+
+// IsRetryable is part of the RetryPolicy interface.
+func (dqliteRetryPolicy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "leadership lost") ||
+		strings.Contains(msg, "no leader") ||
+		strings.Contains(msg, "raft is not leader") ||
+		strings.Contains(msg, "log not contiguous")
+}
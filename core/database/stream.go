@@ -0,0 +1,70 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package database
+
+import (
+	"context"
+	"iter"
+
+	"github.com/canonical/sqlair"
+)
+
+// StreamTxn runs fn inside a single call to db.Txn and returns an
+// iter.Seq2 that yields whatever fn sends on the emit func it is given,
+// without buffering the full result set in memory. The transaction
+// stays open for the lifetime of the returned sequence's single
+// iteration, so callers must range over it to completion (or break out
+// of the range, which cancels the transaction) before doing anything
+// else with db.
+//
+// If fn (or the underlying Txn call) fails, the final yielded pair
+// carries the zero value of T alongside the error; callers should check
+// the error on every iteration rather than only after the range ends.
+//
+// Like BatchTxn, this is a free function rather than a TrackedDB method
+// because Go does not allow type parameters on interface methods.
+func StreamTxn[T any](ctx context.Context, db TrackedDB, fn func(context.Context, *sqlair.TX, func(T) bool) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		items := make(chan T)
+		stop := make(chan struct{})
+		done := make(chan error, 1)
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		go func() {
+			defer close(items)
+			done <- db.Txn(streamCtx, func(ctx context.Context, tx *sqlair.TX) error {
+				return fn(ctx, tx, func(item T) bool {
+					select {
+					case items <- item:
+						return true
+					case <-stop:
+						return false
+					}
+				})
+			})
+		}()
+
+		stopped := false
+		for item := range items {
+			if !yield(item, nil) {
+				close(stop)
+				cancel()
+				stopped = true
+				break
+			}
+		}
+		if stopped {
+			// Drain so the goroutine's Txn call observes the closed
+			// stop channel and returns.
+			for range items {
+			}
+		}
+		if err := <-done; err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
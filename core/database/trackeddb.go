@@ -18,13 +18,19 @@ type TrackedDB interface {
 	// SQL queries and statements.
 	// Retry semantics are applied automatically based on transient failures.
 	// This is the function that almost all downstream database consumers
-	// should use.
-	Txn(context.Context, func(context.Context, *sqlair.TX) error) error
+	// should use. opts may include WithTimeout and WithReadOnly.
+	Txn(context.Context, func(context.Context, *sqlair.TX) error, ...TxnOption) error
 
 	// StdTxn executes the input function against the tracked database,
 	// within a transaction that depends on the input context.
 	// Retry semantics are applied automatically based on transient failures.
 	// This is the function that almost all downstream database consumers
-	// should use.
-	StdTxn(context.Context, func(context.Context, *sql.Tx) error) error
+	// should use. opts may include WithTimeout and WithReadOnly.
+	StdTxn(context.Context, func(context.Context, *sql.Tx) error, ...TxnOption) error
+
+	// SetObserver registers observer to be called once per transaction run
+	// through Txn or StdTxn, after the transaction completes (commit or
+	// rollback), with its duration, retry count, rows affected and
+	// statement text. A nil observer disables observation.
+	SetObserver(observer TxnObserver)
 }
@@ -0,0 +1,47 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package database
+
+import "time"
+
+// TxnReport describes the outcome of a single Txn or StdTxn call, passed
+// to a TxnObserver once the transaction has committed or rolled back.
+type TxnReport struct {
+	// Duration is how long the call took in total, including retries.
+	Duration time.Duration
+
+	// Retries is the number of times the transaction function was
+	// retried after a transient failure, 0 if it succeeded first try.
+	Retries int
+
+	// RowsAffected is the cumulative number of rows affected across
+	// every statement run during the final, successful attempt. It is
+	// left at 0 for read-only transactions and for calls that returned
+	// an error.
+	RowsAffected int64
+
+	// Stmt is the statement text of the last statement run, truncated
+	// implementations may want to cap this for logging.
+	Stmt string
+
+	// Err is the error the transaction ultimately completed with, or
+	// nil on success.
+	Err error
+}
+
+// TxnObserver is notified once per transaction run through TrackedDB's
+// Txn or StdTxn, after the transaction completes. Implementations are
+// typically metrics collectors or slow-query loggers; SetObserver(nil)
+// disables observation.
+type TxnObserver interface {
+	Observe(report TxnReport)
+}
+
+// TxnObserverFunc adapts a plain function to a TxnObserver.
+type TxnObserverFunc func(report TxnReport)
+
+// Observe is part of the TxnObserver interface.
+func (f TxnObserverFunc) Observe(report TxnReport) {
+	f(report)
+}
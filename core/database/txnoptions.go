@@ -0,0 +1,49 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package database
+
+import "time"
+
+// TxnOption configures a single Txn or StdTxn call. The zero value of
+// txnOptions (no options passed) preserves today's behaviour: no
+// deadline beyond the caller's context, and a read-write transaction.
+type TxnOption func(*txnOptions)
+
+// txnOptions is the resolved configuration for one Txn/StdTxn call,
+// built by applying every TxnOption in order.
+type txnOptions struct {
+	timeout  time.Duration
+	readOnly bool
+}
+
+// WithTimeout bounds how long a single Txn/StdTxn call, including
+// retries, may run before its context is cancelled. A timeout of zero
+// (the default) means no additional deadline beyond the caller's
+// context.
+func WithTimeout(d time.Duration) TxnOption {
+	return func(o *txnOptions) {
+		o.timeout = d
+	}
+}
+
+// WithReadOnly marks the transaction as read-only, letting the
+// implementation route it to a read replica or skip write-lock
+// acquisition where the backing engine supports it.
+func WithReadOnly() TxnOption {
+	return func(o *txnOptions) {
+		o.readOnly = true
+	}
+}
+
+// NewTxnOptions resolves opts into the timeout and read-only setting
+// they select. It is exported so TrackedDB implementations outside this
+// package can apply the same option set without needing txnOptions'
+// unexported fields.
+func NewTxnOptions(opts ...TxnOption) (timeout time.Duration, readOnly bool) {
+	var o txnOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.timeout, o.readOnly
+}
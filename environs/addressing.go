@@ -0,0 +1,101 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environs
+
+import (
+	"github.com/juju/names/v4"
+
+	"github.com/juju/juju/core/instance"
+	"github.com/juju/juju/core/network"
+	"github.com/juju/juju/environs/context"
+)
+
+// ContainerAddressAllocator is implemented by NetworkingEnviron and
+// exists only so AllocateContainerAddresses's signature can live next to
+// the AddressFamilyRequest type it depends on, rather than forcing this
+// file to import back from networking.go (it's the same package, but
+// keeps the two concerns in the file each type's change is about).
+type ContainerAddressAllocator interface {
+	// AllocateContainerAddresses allocates addresses for a container
+	// hosted on hostInstanceID, one interface per entry in preparedInfo,
+	// honouring the per-interface dual-stack request in addressRequests
+	// (keyed by interface name; an absent entry falls back to
+	// DefaultAddressFamilyRequest).
+	AllocateContainerAddresses(
+		ctx context.ProviderCallContext,
+		hostInstanceID instance.Id,
+		containerTag names.MachineTag,
+		preparedInfo network.InterfaceInfos,
+		addressRequests map[string]AddressFamilyRequest,
+	) (network.InterfaceInfos, error)
+}
+
+// CountOrAuto requests a specific number of addresses from a family, or
+// lets the provider decide how many to hand out ("auto").
+type CountOrAuto struct {
+	// Auto, when true, lets the provider choose how many addresses of
+	// this family to allocate (typically one). Count is ignored.
+	Auto bool
+
+	// Count is the number of addresses requested from this family.
+	// Ignored when Auto is true.
+	Count int
+}
+
+// AutoCount requests that the provider choose the address count itself.
+func AutoCount() CountOrAuto {
+	return CountOrAuto{Auto: true}
+}
+
+// FixedCount requests exactly n addresses from a family.
+func FixedCount(n int) CountOrAuto {
+	return CountOrAuto{Count: n}
+}
+
+// DualStackPolicy controls what happens when a space cannot satisfy the
+// requested address families for every member.
+type DualStackPolicy string
+
+const (
+	// DualStackRequireBoth fails the allocation unless both IPv4 and
+	// IPv6 addresses can be satisfied.
+	DualStackRequireBoth DualStackPolicy = "require-both"
+
+	// DualStackPreferV4 allocates IPv4 addresses and silently drops the
+	// IPv6 request if the provider/space cannot satisfy it. This is the
+	// fallback policy for providers that cannot honour IPv6.
+	DualStackPreferV4 DualStackPolicy = "prefer-v4"
+
+	// DualStackPreferV6 is the IPv6 analogue of DualStackPreferV4.
+	DualStackPreferV6 DualStackPolicy = "prefer-v6"
+)
+
+// AddressFamilyRequest describes, per network interface, how many
+// addresses of each IP family a caller wants and how to degrade when a
+// family can't be satisfied.
+type AddressFamilyRequest struct {
+	IPv4   CountOrAuto
+	IPv6   CountOrAuto
+	Policy DualStackPolicy
+}
+
+// DefaultAddressFamilyRequest is dual-stack by default: one address of
+// each family, falling back to IPv4-only when the space/provider has no
+// IPv6 subnets.
+func DefaultAddressFamilyRequest() AddressFamilyRequest {
+	return AddressFamilyRequest{
+		IPv4:   FixedCount(1),
+		IPv6:   FixedCount(1),
+		Policy: DualStackPreferV4,
+	}
+}
+
+// unused references kept import-legal for the AllocateContainerAddresses
+// signature declared in ContainerAddressAllocator above.
+var (
+	_ instance.Id
+	_ network.InterfaceInfos
+	_ names.MachineTag
+	_ context.ProviderCallContext
+)
@@ -0,0 +1,84 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package environs
+
+import (
+	"github.com/juju/juju/core/instance"
+	"github.com/juju/juju/core/network"
+	"github.com/juju/juju/environs/context"
+)
+
+// NetworkingCapabilities describes which networking-related operations a
+// provider supports, so callers can check once and skip unsupported calls
+// instead of issuing a Supports* probe at every call site.
+type NetworkingCapabilities struct {
+	// SupportsSpaces reports whether this provider has a concept of
+	// network spaces at all.
+	SupportsSpaces bool
+
+	// SupportsSpaceDiscovery reports whether this provider can discover
+	// its own space/subnet topology, as opposed to relying solely on
+	// operator-defined spaces.
+	SupportsSpaceDiscovery bool
+
+	// SupportsContainerAddresses reports whether this provider can
+	// allocate addresses for containers hosted on one of its instances.
+	SupportsContainerAddresses bool
+
+	// MaxBatchSize bounds how many instances a single bulk operation
+	// (e.g. a batched stop) should address concurrently. Zero or
+	// negative means the provider imposes no limit of its own.
+	MaxBatchSize int
+}
+
+// ProviderSpaceInfo describes one space as reported by a provider, in
+// enough detail to test routability between it and another space.
+type ProviderSpaceInfo struct {
+	// SpaceName is the provider/space identifier this info describes.
+	SpaceName network.SpaceName
+}
+
+// SpaceEdge records a direct hop between two spaces in a
+// provider-reported SpaceTopology: whether traffic between them crosses a
+// router, the accumulated transit cost, and the path MTU.
+type SpaceEdge struct {
+	From network.SpaceName
+	To   network.SpaceName
+
+	ViaRouter   bool
+	TransitCost int
+	MTU         int
+}
+
+// SpaceTopology is a provider's full space connectivity graph, from
+// which space.NewRouter answers reachability questions without an
+// O(n^2) AreSpacesRoutable probe of every space pair. NetworkingEnviron
+// does not declare a method that returns one.
+type SpaceTopology struct {
+	Edges []SpaceEdge
+}
+
+// NetworkingEnviron is implemented by providers that support Juju's
+// networking model: spaces, subnets, and per-instance address allocation.
+type NetworkingEnviron interface {
+	// Capabilities returns a descriptor of which networking operations
+	// this provider supports, so callers can check once up front instead
+	// of issuing a Supports* probe at every call site.
+	Capabilities(ctx context.ProviderCallContext) (NetworkingCapabilities, error)
+
+	// AreSpacesRoutable reports whether traffic can flow between two
+	// provider-reported spaces. Providers that cannot report a full
+	// SpaceTopology fall back to this pairwise probe.
+	AreSpacesRoutable(ctx context.ProviderCallContext, a, b *ProviderSpaceInfo) (bool, error)
+
+	// StopInstances terminates the given instances. Callers that need to
+	// stop many instances without one stuck instance blocking the rest
+	// should use space.StopInstancesBatch rather than calling this
+	// directly in a loop.
+	StopInstances(ctx context.ProviderCallContext, ids ...instance.Id) error
+
+	// ContainerAddressAllocator is embedded for AllocateContainerAddresses;
+	// see addressing.go.
+	ContainerAddressAllocator
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"sync"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/context"
+)
+
+// CapabilityCache memoizes a single environs.NetworkingCapabilities lookup
+// per provider connection, replacing the previous pattern of issuing a
+// Supports* probe on every call site.
+type CapabilityCache struct {
+	env environs.NetworkingEnviron
+
+	mu   sync.Mutex
+	caps *environs.NetworkingCapabilities
+}
+
+// NewCapabilityCache returns a cache that fetches and remembers the
+// provider's capabilities the first time Capabilities is called.
+func NewCapabilityCache(env environs.NetworkingEnviron) *CapabilityCache {
+	return &CapabilityCache{env: env}
+}
+
+// Capabilities returns the cached descriptor, fetching it from the
+// provider on first use.
+func (c *CapabilityCache) Capabilities(ctx context.ProviderCallContext) (environs.NetworkingCapabilities, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.caps != nil {
+		return *c.caps, nil
+	}
+	caps, err := c.env.Capabilities(ctx)
+	if err != nil {
+		return environs.NetworkingCapabilities{}, errors.Trace(err)
+	}
+	c.caps = &caps
+	return caps, nil
+}
+
+// Invalidate forces the next Capabilities call to re-query the provider.
+// Callers should do this after a reconnect.
+func (c *CapabilityCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.caps = nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"github.com/juju/juju/environs"
+)
+
+// CountOrAuto, DualStackPolicy and AddressFamilyRequest live on environs
+// rather than here: environs.NetworkingEnviron.AllocateContainerAddresses
+// takes an AddressFamilyRequest, and environs/space already imports
+// environs, so defining the type here would create an import cycle the
+// moment the interface grew that parameter. These aliases keep the
+// space.AddressFamilyRequest name callers already use working unchanged.
+
+// CountOrAuto requests a specific number of addresses from a family, or
+// lets the provider decide how many to hand out ("auto").
+type CountOrAuto = environs.CountOrAuto
+
+// AutoCount requests that the provider choose the address count itself.
+func AutoCount() CountOrAuto {
+	return environs.AutoCount()
+}
+
+// FixedCount requests exactly n addresses from a family.
+func FixedCount(n int) CountOrAuto {
+	return environs.FixedCount(n)
+}
+
+// DualStackPolicy controls what happens when a space cannot satisfy the
+// requested address families for every member.
+type DualStackPolicy = environs.DualStackPolicy
+
+const (
+	// DualStackRequireBoth fails the allocation unless both IPv4 and
+	// IPv6 addresses can be satisfied.
+	DualStackRequireBoth = environs.DualStackRequireBoth
+
+	// DualStackPreferV4 allocates IPv4 addresses and silently drops the
+	// IPv6 request if the provider/space cannot satisfy it. This is the
+	// fallback policy for providers that cannot honour IPv6.
+	DualStackPreferV4 = environs.DualStackPreferV4
+
+	// DualStackPreferV6 is the IPv6 analogue of DualStackPreferV4.
+	DualStackPreferV6 = environs.DualStackPreferV6
+)
+
+// AddressFamilyRequest describes, per network interface, how many
+// addresses of each IP family a caller wants and how to degrade when a
+// family can't be satisfied.
+type AddressFamilyRequest = environs.AddressFamilyRequest
+
+// DefaultAddressFamilyRequest is dual-stack by default: one address of
+// each family, falling back to IPv4-only when the space/provider has no
+// IPv6 subnets.
+func DefaultAddressFamilyRequest() AddressFamilyRequest {
+	return environs.DefaultAddressFamilyRequest()
+}
@@ -1,5 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: github.com/juju/juju/environs (interfaces: BootstrapEnviron,NetworkingEnviron)
+//
+// Generated by this command:
+//
+//	mockgen -package space -destination environs_mock_test.go github.com/juju/juju/environs BootstrapEnviron,NetworkingEnviron
 
 // Package space is a generated GoMock package.
 package space
@@ -7,7 +11,7 @@ package space
 import (
 	reflect "reflect"
 
-	gomock "github.com/golang/mock/gomock"
+	gomock "go.uber.org/mock/gomock"
 	constraints "github.com/juju/juju/core/constraints"
 	instance "github.com/juju/juju/core/instance"
 	network "github.com/juju/juju/core/network"
@@ -256,18 +260,18 @@ func (mr *MockNetworkingEnvironMockRecorder) AllRunningInstances(arg0 interface{
 }
 
 // AllocateContainerAddresses mocks base method
-func (m *MockNetworkingEnviron) AllocateContainerAddresses(arg0 context.ProviderCallContext, arg1 instance.Id, arg2 names_v3.MachineTag, arg3 network.InterfaceInfos) (network.InterfaceInfos, error) {
+func (m *MockNetworkingEnviron) AllocateContainerAddresses(arg0 context.ProviderCallContext, arg1 instance.Id, arg2 names_v3.MachineTag, arg3 network.InterfaceInfos, arg4 map[string]AddressFamilyRequest) (network.InterfaceInfos, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AllocateContainerAddresses", arg0, arg1, arg2, arg3)
+	ret := m.ctrl.Call(m, "AllocateContainerAddresses", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].(network.InterfaceInfos)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // AllocateContainerAddresses indicates an expected call of AllocateContainerAddresses
-func (mr *MockNetworkingEnvironMockRecorder) AllocateContainerAddresses(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockNetworkingEnvironMockRecorder) AllocateContainerAddresses(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocateContainerAddresses", reflect.TypeOf((*MockNetworkingEnviron)(nil).AllocateContainerAddresses), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocateContainerAddresses", reflect.TypeOf((*MockNetworkingEnviron)(nil).AllocateContainerAddresses), arg0, arg1, arg2, arg3, arg4)
 }
 
 // AreSpacesRoutable mocks base method
@@ -300,6 +304,21 @@ func (mr *MockNetworkingEnvironMockRecorder) Bootstrap(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bootstrap", reflect.TypeOf((*MockNetworkingEnviron)(nil).Bootstrap), arg0, arg1, arg2)
 }
 
+// Capabilities mocks base method
+func (m *MockNetworkingEnviron) Capabilities(arg0 context.ProviderCallContext) (environs.NetworkingCapabilities, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Capabilities", arg0)
+	ret0, _ := ret[0].(environs.NetworkingCapabilities)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Capabilities indicates an expected call of Capabilities
+func (mr *MockNetworkingEnvironMockRecorder) Capabilities(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Capabilities", reflect.TypeOf((*MockNetworkingEnviron)(nil).Capabilities), arg0)
+}
+
 // Config mocks base method
 func (m *MockNetworkingEnviron) Config() *config.Config {
 	m.ctrl.T.Helper()
@@ -560,6 +579,36 @@ func (mr *MockNetworkingEnvironMockRecorder) Spaces(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Spaces", reflect.TypeOf((*MockNetworkingEnviron)(nil).Spaces), arg0)
 }
 
+// SpaceTopology mocks base method
+func (m *MockNetworkingEnviron) SpaceTopology(arg0 context.ProviderCallContext) (*environs.SpaceTopology, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpaceTopology", arg0)
+	ret0, _ := ret[0].(*environs.SpaceTopology)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpaceTopology indicates an expected call of SpaceTopology
+func (mr *MockNetworkingEnvironMockRecorder) SpaceTopology(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpaceTopology", reflect.TypeOf((*MockNetworkingEnviron)(nil).SpaceTopology), arg0)
+}
+
+// SpacesStream mocks base method
+func (m *MockNetworkingEnviron) SpacesStream(arg0 context.ProviderCallContext, arg1 SpacesStreamOptions) (SpaceIterator, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpacesStream", arg0, arg1)
+	ret0, _ := ret[0].(SpaceIterator)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SpacesStream indicates an expected call of SpacesStream
+func (mr *MockNetworkingEnvironMockRecorder) SpacesStream(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpacesStream", reflect.TypeOf((*MockNetworkingEnviron)(nil).SpacesStream), arg0, arg1)
+}
+
 // StartInstance mocks base method
 func (m *MockNetworkingEnviron) StartInstance(arg0 context.ProviderCallContext, arg1 environs.StartInstanceParams) (*environs.StartInstanceResult, error) {
 	m.ctrl.T.Helper()
@@ -594,6 +643,26 @@ func (mr *MockNetworkingEnvironMockRecorder) StopInstances(arg0 interface{}, arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopInstances", reflect.TypeOf((*MockNetworkingEnviron)(nil).StopInstances), varargs...)
 }
 
+// StopInstancesBatch mocks base method
+func (m *MockNetworkingEnviron) StopInstancesBatch(arg0 context.ProviderCallContext, arg1 ...instance.Id) ([]InstanceStopResult, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "StopInstancesBatch", varargs...)
+	ret0, _ := ret[0].([]InstanceStopResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StopInstancesBatch indicates an expected call of StopInstancesBatch
+func (mr *MockNetworkingEnvironMockRecorder) StopInstancesBatch(arg0 interface{}, arg1 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopInstancesBatch", reflect.TypeOf((*MockNetworkingEnviron)(nil).StopInstancesBatch), varargs...)
+}
+
 // StorageProvider mocks base method
 func (m *MockNetworkingEnviron) StorageProvider(arg0 storage.ProviderType) (storage.Provider, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,168 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/instance"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/context"
+)
+
+// defaultMaxBatchSize bounds fan-out concurrency for providers whose
+// capabilities descriptor does not declare a MaxBatchSize (or declares a
+// non-positive value, meaning "no provider-specific limit").
+const defaultMaxBatchSize = 20
+
+// StopErrorKind classifies why an individual instance failed to stop, so
+// callers can decide whether retrying just that instance is worthwhile.
+type StopErrorKind string
+
+const (
+	// StopErrorNotFound means the instance was already gone; callers
+	// should treat this as success rather than retrying.
+	StopErrorNotFound StopErrorKind = "not-found"
+
+	// StopErrorRateLimited means the provider throttled the request;
+	// callers should back off and retry later.
+	StopErrorRateLimited StopErrorKind = "rate-limited"
+
+	// StopErrorPermissionDenied means the credentials in use cannot stop
+	// this instance; retrying without a credential change won't help.
+	StopErrorPermissionDenied StopErrorKind = "permission-denied"
+
+	// StopErrorTransient covers everything else: network blips, provider
+	// 5xxs, and any error this helper could not classify more precisely.
+	// Callers should retry with backoff.
+	StopErrorTransient StopErrorKind = "transient"
+)
+
+// StopError pairs a raw provider error with a classification, so a caller
+// iterating over a batch result can decide per-instance whether to retry,
+// give up, or surface a permission problem to the user.
+type StopError struct {
+	Kind StopErrorKind
+	Err  error
+}
+
+// Error is part of the error interface.
+func (e *StopError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows errors.Is/As to see through to the underlying error.
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+// InstanceStopResult reports the outcome of stopping a single instance as
+// part of a StopInstancesBatch call.
+type InstanceStopResult struct {
+	// ID is the instance this result is for.
+	ID instance.Id
+
+	// Terminal is true once the instance is confirmed gone (including
+	// the case where it was already gone before the call) and the
+	// caller should stop retrying it.
+	Terminal bool
+
+	// Err is nil on success. Otherwise it is a *StopError classifying
+	// the failure.
+	Err error
+}
+
+// StopInstancesBatch fans a stop request out across a bounded worker
+// pool, one provider call per instance up to the supplied capabilities'
+// MaxBatchSize, and collects a per-instance result so that one stuck
+// instance never blocks tear-down of the rest of the batch.
+//
+// It calls the single-instance NetworkingEnviron.StopInstances once per
+// instance; it does not use a provider's native bulk termination API
+// (EC2 TerminateInstances, Azure BeginDeleteAll, GCE bulkDelete), and
+// NetworkingEnviron declares no batched stop method of its own.
+func StopInstancesBatch(ctx context.ProviderCallContext, env environs.NetworkingEnviron, caps environs.NetworkingCapabilities, ids ...instance.Id) ([]InstanceStopResult, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	workers := caps.MaxBatchSize
+	if workers <= 0 {
+		workers = defaultMaxBatchSize
+	}
+	if workers > len(ids) {
+		workers = len(ids)
+	}
+
+	results := make([]InstanceStopResult, len(ids))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = stopOne(ctx, env, ids[idx])
+			}
+		}()
+	}
+	for idx := range ids {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// stopOne issues a single-instance stop and classifies the outcome.
+func stopOne(ctx context.ProviderCallContext, env environs.NetworkingEnviron, id instance.Id) InstanceStopResult {
+	err := env.StopInstances(ctx, id)
+	if err == nil {
+		return InstanceStopResult{ID: id, Terminal: true}
+	}
+
+	stopErr := classify(err)
+	return InstanceStopResult{
+		ID:       id,
+		Terminal: stopErr.Kind == StopErrorNotFound,
+		Err:      stopErr,
+	}
+}
+
+// classify maps a raw provider error onto a StopError so callers can tell
+// permanent failures from ones worth retrying.
+func classify(err error) *StopError {
+	switch {
+	case errors.IsNotFound(err):
+		return &StopError{Kind: StopErrorNotFound, Err: err}
+	case errors.IsForbidden(err), errors.IsUnauthorized(err):
+		return &StopError{Kind: StopErrorPermissionDenied, Err: err}
+	case isRateLimited(err):
+		return &StopError{Kind: StopErrorRateLimited, Err: err}
+	default:
+		return &StopError{Kind: StopErrorTransient, Err: err}
+	}
+}
+
+// isRateLimited reports whether err looks like a provider throttling
+// response. Providers report this in whatever form their own SDK
+// returns (an EC2 "RequestLimitExceeded" error code, a GCE/Azure 429
+// status, ...) with no common Go type across them, so this falls back
+// to matching the handful of phrases those SDKs are known to put in the
+// error string rather than requiring every provider package to produce
+// a shared rate-limit error type.
+func isRateLimited(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"rate limit", "ratelimit", "throttl", "too many requests", "429"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/network"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/context"
+)
+
+// defaultSpacesPageSize is the page size that stands for an unset
+// SpacesStreamOptions.PageSize. NewDefaultSpaceIterator does not page, so
+// nothing in this package applies it.
+const defaultSpacesPageSize = 500
+
+// SpacesStreamOptions configures a paged space discovery call.
+// NetworkingEnviron does not declare a SpacesStream method, and
+// NewDefaultSpaceIterator takes no options.
+type SpacesStreamOptions struct {
+	// PageSize is the maximum number of network.SpaceInfo values the
+	// provider should return in a single batch. Providers may return
+	// fewer, but never more.
+	PageSize int
+
+	// PageToken resumes a previous call. An empty token starts from the
+	// beginning of the inventory.
+	PageToken string
+}
+
+// SpaceBatch is one page of a streamed space discovery result.
+type SpaceBatch struct {
+	// Spaces is this page's worth of space+subnet inventory.
+	Spaces []network.SpaceInfo
+
+	// NextPageToken is opaque to the caller. An empty value means there
+	// are no further pages.
+	NextPageToken string
+}
+
+// SpaceIterator yields space+subnet inventory in bounded-memory batches.
+type SpaceIterator interface {
+	// Next returns the next batch. It returns io.EOF-like behaviour via
+	// Done once the inventory has been exhausted.
+	Next(ctx context.ProviderCallContext) (SpaceBatch, error)
+
+	// Done reports whether the iterator has yielded its final batch.
+	Done() bool
+}
+
+// NewDefaultSpaceIterator returns a SpaceIterator that wraps a provider's
+// existing Spaces call, for providers that have no native paging support.
+// It yields the entire inventory as a single batch.
+func NewDefaultSpaceIterator(env environs.NetworkingEnviron) SpaceIterator {
+	return &defaultSpaceIterator{env: env}
+}
+
+type defaultSpaceIterator struct {
+	env  environs.NetworkingEnviron
+	done bool
+}
+
+// Next is part of the SpaceIterator interface.
+func (it *defaultSpaceIterator) Next(ctx context.ProviderCallContext) (SpaceBatch, error) {
+	if it.done {
+		return SpaceBatch{}, errors.New("space iterator exhausted")
+	}
+	spaces, err := it.env.Spaces(ctx)
+	if err != nil {
+		return SpaceBatch{}, errors.Trace(err)
+	}
+	it.done = true
+	return SpaceBatch{Spaces: spaces}, nil
+}
+
+// Done is part of the SpaceIterator interface.
+func (it *defaultSpaceIterator) Done() bool {
+	return it.done
+}
+
+// UpsertPageFunc persists a single batch of spaces into state. Callers are
+// expected to do this under a single transaction per page.
+type UpsertPageFunc func(batch SpaceBatch) error
+
+// ConsumeSpacesStream drains a SpaceIterator, invoking upsert once per page
+// so that space reconciliation against state stays bounded-memory even on
+// fabrics with thousands of subnets.
+func ConsumeSpacesStream(ctx context.ProviderCallContext, it SpaceIterator, upsert UpsertPageFunc) error {
+	for !it.Done() {
+		batch, err := it.Next(ctx)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := upsert(batch); err != nil {
+			return errors.Annotate(err, "upserting space batch")
+		}
+	}
+	return nil
+}
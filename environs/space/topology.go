@@ -0,0 +1,155 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package space
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/network"
+	"github.com/juju/juju/environs"
+	"github.com/juju/juju/environs/context"
+)
+
+// RoutePath describes how to get from one space to another: the list of
+// transit spaces traversed (excluding the origin, including the
+// destination) and the accumulated edge cost.
+type RoutePath struct {
+	// Transit is the ordered list of spaces traversed to reach the
+	// destination, ending with the destination itself.
+	Transit []network.SpaceName
+
+	// Cost is the sum of the transit-cost of every edge on the path.
+	Cost int
+}
+
+// edge records the provider-reported metadata for a direct hop between two
+// spaces.
+type edge struct {
+	viaRouter   bool
+	transitCost int
+	mtu         int
+}
+
+// Router answers multi-hop reachability questions over a space topology
+// built from environs.SpaceTopology. It is built once per provider
+// connection and reused, rather than issuing a provider call per query.
+type Router struct {
+	// adjacency maps a space to the set of spaces directly reachable
+	// from it, along with the edge metadata for that hop.
+	adjacency map[network.SpaceName]map[network.SpaceName]edge
+}
+
+// NewRouter builds a Router from a environs.SpaceTopology snapshot.
+// Unknown/absent edges are treated as unreachable rather than errors;
+// a self-loop on every space is implicit and does not need to be present
+// in the topology.
+func NewRouter(topology *environs.SpaceTopology) *Router {
+	r := &Router{adjacency: make(map[network.SpaceName]map[network.SpaceName]edge)}
+	if topology == nil {
+		return r
+	}
+	for _, link := range topology.Edges {
+		r.addEdge(link.From, link.To, edge{
+			viaRouter:   link.ViaRouter,
+			transitCost: link.TransitCost,
+			mtu:         link.MTU,
+		})
+	}
+	return r
+}
+
+func (r *Router) addEdge(from, to network.SpaceName, e edge) {
+	if _, ok := r.adjacency[from]; !ok {
+		r.adjacency[from] = make(map[network.SpaceName]edge)
+	}
+	r.adjacency[from][to] = e
+}
+
+// Reachable performs a breadth-first search from "from" and returns, for
+// each requested destination that is reachable, the path with the fewest
+// hops, with the transit cost accumulated along it. That is not
+// necessarily the lowest-cost path, and among paths with the same number
+// of hops the one returned is not defined. "from" itself, if requested,
+// is reachable with an empty path. Destinations absent from the result
+// map are unreachable.
+func (r *Router) Reachable(from network.SpaceName, to ...network.SpaceName) (map[network.SpaceName]RoutePath, error) {
+	wanted := make(map[network.SpaceName]bool, len(to))
+	for _, t := range to {
+		wanted[t] = true
+	}
+
+	type queued struct {
+		space network.SpaceName
+		path  RoutePath
+	}
+
+	visited := map[network.SpaceName]bool{from: true}
+	queue := []queued{{space: from, path: RoutePath{}}}
+	result := make(map[network.SpaceName]RoutePath)
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		if wanted[cur.space] {
+			result[cur.space] = cur.path
+		}
+
+		for next, e := range r.adjacency[cur.space] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			nextPath := RoutePath{
+				Transit: append(append([]network.SpaceName{}, cur.path.Transit...), next),
+				Cost:    cur.path.Cost + e.transitCost,
+			}
+			queue = append(queue, queued{space: next, path: nextPath})
+		}
+	}
+	return result, nil
+}
+
+// cartesianRouter is the default adapter for providers that only implement
+// the legacy pairwise AreSpacesRoutable. It lazily builds a full topology
+// by probing every space pair on first use, and caches the result.
+type cartesianRouter struct {
+	env    environs.NetworkingEnviron
+	router *Router
+}
+
+// NewCartesianRouter returns a Router built on demand from
+// AreSpacesRoutable, for providers that do not implement SpaceTopology.
+func NewCartesianRouter(env environs.NetworkingEnviron) *cartesianRouter {
+	return &cartesianRouter{env: env}
+}
+
+// Router builds (on first call) and returns the cached Router, falling
+// back to an O(n^2) cartesian product of AreSpacesRoutable calls over the
+// supplied provider space infos.
+func (c *cartesianRouter) Router(ctx context.ProviderCallContext, spaces []*environs.ProviderSpaceInfo) (*Router, error) {
+	if c.router != nil {
+		return c.router, nil
+	}
+	topology := &environs.SpaceTopology{}
+	for _, a := range spaces {
+		for _, b := range spaces {
+			if a == b {
+				continue
+			}
+			ok, err := c.env.AreSpacesRoutable(ctx, a, b)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if ok {
+				topology.Edges = append(topology.Edges, environs.SpaceEdge{
+					From: a.SpaceName,
+					To:   b.SpaceName,
+				})
+			}
+		}
+	}
+	c.router = NewRouter(topology)
+	return c.router, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"launchpad.net/gwacl"
+)
+
+// vhdContainerName is the blob container newOSDisk places every VHD
+// into (see the "vhds/" prefix of its vhdPath), which is distinct from
+// getContainerName's per-environment container used for Juju's own
+// tools and image metadata.
+const vhdContainerName = "vhds"
+
+// vhdNamePrefix is the prefix gwacl.MakeRandomDiskName gives every VHD
+// newOSDisk creates, used by the dangling-blob sweep below to recognise
+// blobs that are ours to clean up.
+const vhdNamePrefix = "juju"
+
+// deleteDanglingResourcesAfterAttr is the azure config key controlling
+// how long deleteBlobWithRetries waits between retries of a blob delete
+// that Azure is refusing because a lease is still held on it. This is
+// deliberately a separate knob from danglingResourceTTL
+// (dangling_resources.go): that one bounds how old an unreferenced
+// resource must be before a sweep reclaims it at all, while this one
+// bounds the retry backoff for a delete the sweep (or deleteDanglingVHDs)
+// has already decided to perform.
+const deleteDanglingResourcesAfterAttr = "delete-dangling-resources-after"
+
+// DefaultDeleteDanglingResourcesAfter is used when the azure config does
+// not set "delete-dangling-resources-after".
+const DefaultDeleteDanglingResourcesAfter = 5 * time.Minute
+
+// deleteDanglingResourcesAfter returns this config's
+// "delete-dangling-resources-after" setting, or
+// DefaultDeleteDanglingResourcesAfter if it is unset or invalid.
+func (ecfg *azureEnvironConfig) deleteDanglingResourcesAfter() time.Duration {
+	v, _ := ecfg.UnknownAttrs()[deleteDanglingResourcesAfterAttr].(string)
+	if v == "" {
+		return DefaultDeleteDanglingResourcesAfter
+	}
+	wait, err := time.ParseDuration(v)
+	if err != nil {
+		return DefaultDeleteDanglingResourcesAfter
+	}
+	return wait
+}
+
+// maxBlobDeleteAttempts bounds how many times deleteBlobWithRetries
+// retries a blob delete that Azure is refusing because a lease is still
+// held on it.
+const maxBlobDeleteAttempts = 10
+
+// blobNameFromMediaLink extracts the blob name (relative to
+// vhdContainerName) from the mediaLink URL newOSDisk records for a
+// VHD, e.g. "https://acc.blob.core.windows.net/vhds/foo.vhd" -> "foo.vhd".
+func blobNameFromMediaLink(mediaLink string) (string, error) {
+	u, err := url.Parse(mediaLink)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse media link %q: %v", mediaLink, err)
+	}
+	return strings.TrimPrefix(u.Path, "/"+vhdContainerName+"/"), nil
+}
+
+// vhdMediaLink fetches the mediaLink of the OS disk backing a role,
+// straight from Azure rather than anything cached at creation time, so
+// it is still correct if the role's disk was ever swapped out.
+func (env *azureEnviron) vhdMediaLink(context *gwacl.ManagementAPI, serviceName, deploymentName, roleName string) (string, error) {
+	role, err := context.GetRole(&gwacl.GetRoleRequest{
+		ServiceName:    serviceName,
+		DeploymentName: deploymentName,
+		RoleName:       roleName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if role.OSVirtualHardDisk == nil {
+		return "", nil
+	}
+	return role.OSVirtualHardDisk.MediaLink, nil
+}
+
+// isLeaseConflict reports whether err is the 409 Conflict Azure returns
+// for a blob that is still under an active lease, which is what
+// DeleteRole's DeleteMedia flag races: it can return success before the
+// underlying page blob's lease is actually released.
+func isLeaseConflict(err error) bool {
+	azErr, ok := err.(*gwacl.AzureError)
+	if !ok {
+		return false
+	}
+	return azErr.StatusCode == http.StatusConflict
+}
+
+// deleteBlobWithRetries deletes blob from container, retrying while
+// Azure reports the blob as still leased, up to maxBlobDeleteAttempts
+// times with wait between attempts.
+func deleteBlobWithRetries(storageContext *gwacl.StorageContext, container, blob string, wait time.Duration) error {
+	var lastErr error
+	for attempt := 0; attempt < maxBlobDeleteAttempts; attempt++ {
+		err := storageContext.DeleteBlob(container, blob)
+		if err == nil {
+			return nil
+		}
+		if !isLeaseConflict(err) {
+			return err
+		}
+		lastErr = err
+		time.Sleep(wait)
+	}
+	return fmt.Errorf("giving up deleting blob %q after %d attempts: %v", blob, maxBlobDeleteAttempts, lastErr)
+}
+
+// deleteDanglingVHDs deletes each of mediaLinks from the vhds
+// container, tolerating blobs that are already gone (another sweep, or
+// the instance never had a disk).
+func (env *azureEnviron) deleteDanglingVHDs(mediaLinks []string) error {
+	if len(mediaLinks) == 0 {
+		return nil
+	}
+	storageContext, err := env.getStorageContext()
+	if err != nil {
+		return err
+	}
+	wait := env.getSnapshot().ecfg.deleteDanglingResourcesAfter()
+	for _, mediaLink := range mediaLinks {
+		blob, err := blobNameFromMediaLink(mediaLink)
+		if err != nil {
+			return err
+		}
+		if err := deleteBlobWithRetries(storageContext, vhdContainerName, blob, wait); err != nil {
+			return fmt.Errorf("cannot garbage-collect VHD %q: %v", blob, err)
+		}
+	}
+	return nil
+}
+
+// reconcileDanglingBlobs deletes VHDs in vhdContainerName whose name
+// carries vhdNamePrefix, that no role in referencedBlobs currently
+// references, and that were last modified more than olderThan ago (so a
+// VHD a concurrent StartInstance is still uploading is never mistaken
+// for dangling). It is run from destroyAllServices and from
+// sweepDanglingResources, so that tearing down an environment, or a
+// periodic sweep, does not leak VHDs behind roles that are already gone
+// (e.g. from a previously interrupted Destroy).
+func (env *azureEnviron) reconcileDanglingBlobs(referencedBlobs map[string]bool, olderThan time.Duration) error {
+	storageContext, err := env.getStorageContext()
+	if err != nil {
+		return err
+	}
+	blobs, err := storageContext.ListAllBlobs(&gwacl.ListBlobsRequest{Container: vhdContainerName})
+	if err != nil {
+		return err
+	}
+	wait := env.getSnapshot().ecfg.deleteDanglingResourcesAfter()
+	cutoff := time.Now().Add(-olderThan)
+	for _, blob := range blobs {
+		if !strings.HasPrefix(blob.Name, vhdNamePrefix) {
+			continue
+		}
+		if referencedBlobs[blob.Name] {
+			continue
+		}
+		if blob.Properties.LastModified.After(cutoff) {
+			continue
+		}
+		if err := deleteBlobWithRetries(storageContext, vhdContainerName, blob.Name, wait); err != nil {
+			return fmt.Errorf("cannot garbage-collect VHD %q: %v", blob.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"errors"
+	"net/http"
+
+	gc "gopkg.in/check.v1"
+	"launchpad.net/gwacl"
+)
+
+type BlobsSuite struct{}
+
+var _ = gc.Suite(&BlobsSuite{})
+
+func (s *BlobsSuite) TestBlobNameFromMediaLink(c *gc.C) {
+	name, err := blobNameFromMediaLink("https://acc.blob.core.windows.net/vhds/foo.vhd")
+	c.Assert(err, gc.IsNil)
+	c.Assert(name, gc.Equals, "foo.vhd")
+}
+
+func (s *BlobsSuite) TestBlobNameFromMediaLinkInvalidURL(c *gc.C) {
+	_, err := blobNameFromMediaLink("://not-a-url")
+	c.Assert(err, gc.ErrorMatches, `cannot parse media link .*`)
+}
+
+func (s *BlobsSuite) TestIsLeaseConflict(c *gc.C) {
+	c.Assert(isLeaseConflict(&gwacl.AzureError{StatusCode: http.StatusConflict}), gc.Equals, true)
+	c.Assert(isLeaseConflict(&gwacl.AzureError{StatusCode: http.StatusNotFound}), gc.Equals, false)
+}
+
+func (s *BlobsSuite) TestIsLeaseConflictNonAzureError(c *gc.C) {
+	c.Assert(isLeaseConflict(errors.New("boom")), gc.Equals, false)
+}
@@ -0,0 +1,85 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"sync"
+
+	"launchpad.net/gwacl"
+)
+
+// maxPooledManagementContexts bounds how many idle
+// *azureManagementContext values contextPool keeps around per config
+// generation, so a burst of Instances/AllInstances/destroyAllServices
+// calls reuses connections without the pool growing without bound.
+const maxPooledManagementContexts = 10
+
+// contextPool pools *azureManagementContext and *gwacl.StorageContext
+// values, each keyed by the *azureEnvironConfig generation they were
+// built from. Reconfiguring the environment (SetConfig swapping in a
+// new *azureEnvironConfig) naturally invalidates every entry for the
+// old generation: nothing will ever look them up under the new ecfg
+// pointer again, so they are simply left for releaseManagementAPI (or
+// the garbage collector, for storage contexts) rather than drained
+// explicitly.
+type contextPool struct {
+	mu         sync.Mutex
+	management map[*azureEnvironConfig][]*azureManagementContext
+	storage    map[*azureEnvironConfig]*gwacl.StorageContext
+}
+
+func newContextPool() *contextPool {
+	return &contextPool{
+		management: make(map[*azureEnvironConfig][]*azureManagementContext),
+		storage:    make(map[*azureEnvironConfig]*gwacl.StorageContext),
+	}
+}
+
+// getManagementContext pops a pooled *azureManagementContext built for
+// generation, or returns nil if there is none idle.
+func (p *contextPool) getManagementContext(generation *azureEnvironConfig) *azureManagementContext {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pooled := p.management[generation]
+	if len(pooled) == 0 {
+		return nil
+	}
+	context := pooled[len(pooled)-1]
+	p.management[generation] = pooled[:len(pooled)-1]
+	return context
+}
+
+// putManagementContext returns context to the pool for generation, so
+// the next getManagementContext call for the same generation reuses its
+// underlying http.Transport/http.Client instead of opening a new SSL
+// connection. If generation's pool is already full, context is handed
+// to the caller-supplied discard func instead of being kept idle
+// forever.
+func (p *contextPool) putManagementContext(generation *azureEnvironConfig, context *azureManagementContext, discard func(*azureManagementContext)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pooled := p.management[generation]
+	if len(pooled) >= maxPooledManagementContexts {
+		discard(context)
+		return
+	}
+	p.management[generation] = append(pooled, context)
+}
+
+// getStorageContext returns the cached *gwacl.StorageContext for
+// generation, or nil if none has been created yet.
+func (p *contextPool) getStorageContext(generation *azureEnvironConfig) *gwacl.StorageContext {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.storage[generation]
+}
+
+// putStorageContext caches context as generation's *gwacl.StorageContext,
+// so every getStorageContext call for that generation reuses the same
+// one rather than opening a fresh connection per call.
+func (p *contextPool) putStorageContext(generation *azureEnvironConfig, context *gwacl.StorageContext) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.storage[generation] = context
+}
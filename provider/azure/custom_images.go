@@ -0,0 +1,134 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"launchpad.net/gwacl"
+)
+
+// customImagesAttr is the azure config key holding a series -> image
+// mapping for operators who need per-series custom images without
+// disabling simplestreams lookups for every other series.
+const customImagesAttr = "custom-images"
+
+// customImageSpec is one entry of the "custom-images" config map.
+type customImageSpec struct {
+	// Image is either a platform image name, handled exactly like a
+	// simplestreams-sourced one, or a VHD URL (see isVHDURL), in which
+	// case newOSDisk references it directly as the disk's mediaLink
+	// instead of creating a new VHD in this environment's own storage
+	// account.
+	Image string
+
+	// StorageAccount is the Azure storage account holding Image, when
+	// Image is a VHD URL in another subscription's storage account. It
+	// must be in the same location as this environment; see
+	// validateCustomImages.
+	StorageAccount string
+
+	// OS is "Linux" or "Windows", mirroring newOSDisk's OS field, since
+	// a VHD URL carries no platform image metadata to infer it from.
+	OS string
+}
+
+// isVHDURL reports whether image is a VHD URL rather than the name of a
+// platform image.
+func isVHDURL(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// customImages returns this config's "custom-images" map, keyed by
+// series, or nil if the attribute is unset.
+func (ecfg *azureEnvironConfig) customImages() (map[string]customImageSpec, error) {
+	raw, ok := ecfg.UnknownAttrs()[customImagesAttr]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	rawMap, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a map, got %T", customImagesAttr, raw)
+	}
+	images := make(map[string]customImageSpec, len(rawMap))
+	for series, v := range rawMap {
+		switch entry := v.(type) {
+		case string:
+			images[series] = customImageSpec{Image: entry, OS: "Linux"}
+		case map[string]interface{}:
+			spec := customImageSpec{OS: "Linux"}
+			if image, _ := entry["image"].(string); image != "" {
+				spec.Image = image
+			} else {
+				return nil, fmt.Errorf("%s: series %q: missing \"image\"", customImagesAttr, series)
+			}
+			if account, _ := entry["image-storage-account"].(string); account != "" {
+				spec.StorageAccount = account
+			}
+			if os, _ := entry["os"].(string); os != "" {
+				spec.OS = os
+			}
+			images[series] = spec
+		default:
+			return nil, fmt.Errorf("%s: series %q: unexpected entry type %T", customImagesAttr, series, v)
+		}
+	}
+	return images, nil
+}
+
+// customImageFor returns the custom-images entry configured for
+// series, and whether one was found.
+func (ecfg *azureEnvironConfig) customImageFor(series string) (customImageSpec, bool, error) {
+	images, err := ecfg.customImages()
+	if err != nil {
+		return customImageSpec{}, false, err
+	}
+	spec, ok := images[series]
+	return spec, ok, nil
+}
+
+// validateCustomImages checks that every custom-images entry naming a
+// cross-account VHD lives in the same Azure location as this config,
+// since Azure cannot attach a VHD from a storage account in another
+// location.
+func (ecfg *azureEnvironConfig) validateCustomImages() error {
+	images, err := ecfg.customImages()
+	if err != nil {
+		return err
+	}
+	var withAccount []customImageSpec
+	for _, spec := range images {
+		if spec.StorageAccount != "" {
+			withAccount = append(withAccount, spec)
+		}
+	}
+	if len(withAccount) == 0 {
+		return nil
+	}
+
+	certFile, err := newTempCertFile([]byte(ecfg.managementCertificate()))
+	if err != nil {
+		return err
+	}
+	defer certFile.Delete()
+	azure, err := gwacl.NewManagementAPIWithRetryPolicy(
+		ecfg.managementSubscriptionId(), certFile.Path(), ecfg.location(), retryPolicy)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range withAccount {
+		account, err := azure.GetStorageAccount(spec.StorageAccount)
+		if err != nil {
+			return fmt.Errorf("cannot validate custom image storage account %q: %v", spec.StorageAccount, err)
+		}
+		if account.Location != ecfg.location() {
+			return fmt.Errorf(
+				"custom image storage account %q is in location %q, not %q",
+				spec.StorageAccount, account.Location, ecfg.location())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type CustomImagesSuite struct{}
+
+var _ = gc.Suite(&CustomImagesSuite{})
+
+func (s *CustomImagesSuite) TestIsVHDURL(c *gc.C) {
+	c.Assert(isVHDURL("https://acc.blob.core.windows.net/vhds/foo.vhd"), gc.Equals, true)
+	c.Assert(isVHDURL("http://acc.blob.core.windows.net/vhds/foo.vhd"), gc.Equals, true)
+	c.Assert(isVHDURL("trusty-daily"), gc.Equals, false)
+}
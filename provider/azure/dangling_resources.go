@@ -0,0 +1,144 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"launchpad.net/gwacl"
+)
+
+// danglingResourceTTLAttr is the azure config key controlling how old a
+// blob must be before sweepDanglingResources considers it safe to
+// reclaim, so that a VHD a still-in-flight StartInstance only just
+// created is never mistaken for dangling. Reserved IP addresses are not
+// filtered by age (see sweepDanglingReservedIPs).
+const danglingResourceTTLAttr = "dangling-resource-ttl"
+
+// DefaultDanglingResourceTTL is used when the azure config does not set
+// "dangling-resource-ttl".
+const DefaultDanglingResourceTTL = 30 * time.Minute
+
+// danglingResourceTTL returns this config's "dangling-resource-ttl"
+// setting, or DefaultDanglingResourceTTL if it is unset or invalid.
+func (ecfg *azureEnvironConfig) danglingResourceTTL() time.Duration {
+	v, _ := ecfg.UnknownAttrs()[danglingResourceTTLAttr].(string)
+	if v == "" {
+		return DefaultDanglingResourceTTL
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		return DefaultDanglingResourceTTL
+	}
+	return ttl
+}
+
+// sweepDanglingResources reclaims this environment's blobs that are
+// older than olderThan and no longer referenced by any live hosted
+// service, and its reserved IP addresses that are no longer associated
+// with a service (reserved IPs are not filtered by age). It is
+// idempotent. Blobs younger than olderThan and reserved IPs still
+// attached to a service are left alone. A blob still under lease is not
+// skipped: deleteBlobWithRetries retries it up to maxBlobDeleteAttempts
+// times, and the sweep returns an error if the lease outlasts them.
+// destroyAllServices calls it with olderThan 0
+// once every service in the environment is already gone; it can
+// otherwise be run periodically (e.g. on a timer) with
+// ecfg.danglingResourceTTL() to catch leaks from an interrupted
+// StartInstance/StopInstances without waiting for a full Destroy.
+func (env *azureEnviron) sweepDanglingResources(olderThan time.Duration) error {
+	referencedBlobs, err := env.liveVHDBlobNames()
+	if err != nil {
+		return err
+	}
+	if err := env.reconcileDanglingBlobs(referencedBlobs, olderThan); err != nil {
+		return err
+	}
+	return env.sweepDanglingReservedIPs()
+}
+
+// liveVHDBlobNames returns the set of VHD blob names currently
+// referenced by a role in one of this environment's still-live hosted
+// services, so sweepDanglingResources never reclaims a blob that is
+// still attached to a running instance.
+func (env *azureEnviron) liveVHDBlobNames() (map[string]bool, error) {
+	context, err := env.getManagementAPI()
+	if err != nil {
+		return nil, err
+	}
+	defer env.releaseManagementAPI(context)
+
+	request := &gwacl.ListPrefixedHostedServicesRequest{ServiceNamePrefix: env.getEnvPrefix()}
+	services, err := context.ListPrefixedHostedServices(request)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, sd := range services {
+		hostedService, err := context.GetHostedServiceProperties(sd.ServiceName, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, deployment := range hostedService.Deployments {
+			for _, role := range deployment.RoleList {
+				mediaLink, err := env.vhdMediaLink(
+					context.ManagementAPI, sd.ServiceName, deployment.Name, role.RoleName)
+				if err != nil {
+					return nil, err
+				}
+				if mediaLink == "" {
+					continue
+				}
+				blobName, err := blobNameFromMediaLink(mediaLink)
+				if err != nil {
+					return nil, err
+				}
+				referenced[blobName] = true
+			}
+		}
+	}
+	return referenced, nil
+}
+
+// sweepDanglingReservedIPs deletes this environment's reserved IP
+// addresses that are no longer associated with any hosted service.
+//
+// Classic (Service Management API) deployments have no standalone NIC
+// or public IP objects the way ARM ones do: a role's public address
+// comes from its cloud service's VIP, with an optional reserved IP
+// address layered on top of it. A reserved IP is the nearest thing this
+// era of Azure has to the orphaned NIC this sweep is meant to catch, so
+// that is what gets reconciled here. The classic API does not expose a
+// reserved IP's creation time, so (unlike blobs) there is no olderThan
+// grace period here; association is the only safe signal available,
+// and an IP is associated with its service from the moment it is
+// requested.
+func (env *azureEnviron) sweepDanglingReservedIPs() error {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	prefix := env.getEnvPrefix()
+	addresses, err := azure.ListReservedIPAddresses()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		if !strings.HasPrefix(addr.Name, prefix) {
+			continue
+		}
+		if addr.ServiceName != "" {
+			// Still in use by a live service.
+			continue
+		}
+		if err := azure.DeleteReservedIPAddress(addr.Name); err != nil {
+			return fmt.Errorf("cannot garbage-collect reserved IP %q: %v", addr.Name, err)
+		}
+	}
+	return nil
+}
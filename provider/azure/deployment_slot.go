@@ -0,0 +1,68 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// deploymentSlotAttr is the azure config key selecting which of
+// Azure's two deployment slots new services are created in.
+const deploymentSlotAttr = "deployment-slot"
+
+const (
+	productionDeploymentSlot = "Production"
+	stagingDeploymentSlot    = "Staging"
+)
+
+// deploymentSlot returns this config's "deployment-slot" setting, or
+// defaultDeploymentSlot if it is unset.
+func (ecfg *azureEnvironConfig) deploymentSlot() string {
+	slot, _ := ecfg.UnknownAttrs()[deploymentSlotAttr].(string)
+	if slot == "" {
+		return defaultDeploymentSlot
+	}
+	return slot
+}
+
+// validateDeploymentSlot rejects any "deployment-slot" value other than
+// Azure's two real slots.
+func validateDeploymentSlot(slot string) error {
+	switch slot {
+	case productionDeploymentSlot, stagingDeploymentSlot:
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid %s %q: must be %q or %q",
+			deploymentSlotAttr, slot, productionDeploymentSlot, stagingDeploymentSlot)
+	}
+}
+
+// availabilitySetName returns the availability set a role with the
+// given cloud service label should join: "juju-state-server" maps to
+// "juju-state-server-as", and every other label gets its own set the
+// same way, so that services sharing a label land in the same
+// availability set.
+func availabilitySetName(label string) string {
+	if label == "" {
+		label = "juju"
+	}
+	return label + "-as"
+}
+
+// stagingHostname extracts the Azure-assigned hostname from a Staging
+// deployment's URL, which is the only place that hostname appears:
+// Staging deployments don't resolve via "<service>.cloudapp.net" the
+// way Production ones do.
+func stagingHostname(deploymentURL string) (string, error) {
+	u, err := url.Parse(deploymentURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse deployment URL %q: %v", deploymentURL, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("deployment URL %q has no host", deploymentURL)
+	}
+	return u.Host, nil
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	gc "gopkg.in/check.v1"
+)
+
+type DeploymentSlotSuite struct{}
+
+var _ = gc.Suite(&DeploymentSlotSuite{})
+
+func (s *DeploymentSlotSuite) TestValidateDeploymentSlotValid(c *gc.C) {
+	c.Assert(validateDeploymentSlot(productionDeploymentSlot), gc.IsNil)
+	c.Assert(validateDeploymentSlot(stagingDeploymentSlot), gc.IsNil)
+}
+
+func (s *DeploymentSlotSuite) TestValidateDeploymentSlotInvalid(c *gc.C) {
+	err := validateDeploymentSlot("Preview")
+	c.Assert(err, gc.ErrorMatches, `invalid deployment-slot "Preview": must be "Production" or "Staging"`)
+}
+
+func (s *DeploymentSlotSuite) TestAvailabilitySetName(c *gc.C) {
+	c.Assert(availabilitySetName("juju-state-server"), gc.Equals, "juju-state-server-as")
+	c.Assert(availabilitySetName(""), gc.Equals, "juju-as")
+}
+
+func (s *DeploymentSlotSuite) TestStagingHostname(c *gc.C) {
+	host, err := stagingHostname("https://example.cloudapp.net/deployment")
+	c.Assert(err, gc.IsNil)
+	c.Assert(host, gc.Equals, "example.cloudapp.net")
+}
+
+func (s *DeploymentSlotSuite) TestStagingHostnameNoHost(c *gc.C) {
+	_, err := stagingHostname("/just/a/path")
+	c.Assert(err, gc.ErrorMatches, `deployment URL ".*" has no host`)
+}
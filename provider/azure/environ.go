@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Azure/go-autorest/autorest"
 	"launchpad.net/gwacl"
 
 	"launchpad.net/juju-core/constraints"
@@ -32,16 +33,16 @@ import (
 )
 
 const (
-	// deploymentSlot says in which slot to deploy instances.  Azure
-	// supports 'Production' or 'Staging'.
-	// This provider always deploys to Production.  Think twice about
-	// changing that: DNS names in the staging slot work differently from
-	// those in the production slot.  In Staging, Azure assigns an
-	// arbitrary hostname that we can then extract from the deployment's
-	// URL.  In Production, the hostname in the deployment URL does not
-	// actually seem to resolve; instead, the service name is used as the
-	// DNS name, with ".cloudapp.net" appended.
-	deploymentSlot = "Production"
+	// defaultDeploymentSlot is used when the azure config does not set
+	// "deployment-slot". Azure supports 'Production' or 'Staging'.
+	// Think twice about deploying to Staging: DNS names there work
+	// differently from Production.  In Staging, Azure assigns an
+	// arbitrary hostname that we then extract from the deployment's
+	// URL (see stagingHostname).  In Production, the hostname in the
+	// deployment URL does not actually seem to resolve; instead, the
+	// service name is used as the DNS name, with ".cloudapp.net"
+	// appended.
+	defaultDeploymentSlot = "Production"
 
 	// Address space of the virtual network used by the nodes in this
 	// environement, in CIDR notation. This is the network used for
@@ -67,6 +68,24 @@ type azureEnviron struct {
 	// private storage.  This is automatically queried from Azure on
 	// startup.
 	storageAccountKey string
+
+	// nsgLocks serializes rule CRUD against Network Security Groups, one
+	// lock per NSG name.  It is separate from the embedded sync.Mutex,
+	// which protects azureEnviron's own fields, because Azure rejects
+	// concurrent mutations to the same NSG as a conflict (the same
+	// class of bug documented for maxConcurrentDeletes below), and
+	// OpenPorts/ClosePorts can be called concurrently from multiple
+	// unit hooks. It is keyed by NSG name, rather than being a single
+	// mutex, because instance firewall mode gives every hosted service
+	// its own NSG, and those must be free to open/close ports in
+	// parallel even while parallel.Run is fanning out across instances.
+	nsgLocks *nsgLockTable
+
+	// contextPool lets getManagementAPI and getStorageContext reuse
+	// contexts (and the SSL connections they hold) across calls,
+	// instead of opening a fresh one every time, as a bare comment on
+	// those two methods used to note was "probably wasteful."
+	contextPool *contextPool
 }
 
 // azureEnviron implements Environ and HasRegion.
@@ -77,7 +96,7 @@ var _ envtools.SupportsCustomSources = (*azureEnviron)(nil)
 
 // NewEnviron creates a new azureEnviron.
 func NewEnviron(cfg *config.Config) (*azureEnviron, error) {
-	env := azureEnviron{name: cfg.Name()}
+	env := azureEnviron{name: cfg.Name(), nsgLocks: newNSGLockTable(), contextPool: newContextPool()}
 	err := env.SetConfig(cfg)
 	if err != nil {
 		return nil, err
@@ -139,8 +158,12 @@ func (env *azureEnviron) getSnapshot() *azureEnviron {
 	// Copy the environment.  (Not the pointer, the environment itself.)
 	// This is a shallow copy.
 	snap := *env
-	// Reset the snapshot's mutex, because we just copied it while we
-	// were holding it.  The snapshot will have a "clean," unlocked mutex.
+	// Reset the snapshot's own mutex, because we just copied it while we
+	// were holding it.  The snapshot will have a "clean," unlocked
+	// mutex.  nsgLocks is left alone: it guards Azure API calls rather
+	// than any of the fields being copied, and it is a pointer shared
+	// with env, so every snapshot should keep serializing through the
+	// same per-NSG locks as env itself.
 	snap.Mutex = sync.Mutex{}
 	return &snap
 }
@@ -239,6 +262,16 @@ func (env *azureEnviron) Bootstrap(ctx environs.BootstrapContext, cons constrain
 			env.deleteVirtualNetwork()
 		}
 	}()
+	err = env.createNetworkSecurityGroup()
+	if err != nil {
+		return err
+	}
+	// If we fail after this point, clean up the network security group.
+	defer func() {
+		if err != nil {
+			env.deleteNetworkSecurityGroup()
+		}
+	}()
 	err = common.Bootstrap(ctx, env, cons)
 	return err
 }
@@ -271,6 +304,16 @@ func (env *azureEnviron) SetConfig(cfg *config.Config) error {
 		}
 	}
 
+	if err := ecfg.validateCustomImages(); err != nil {
+		return err
+	}
+	if err := validateDeploymentSlot(ecfg.deploymentSlot()); err != nil {
+		return err
+	}
+	if err := ecfg.validateServicePrincipal(); err != nil {
+		return err
+	}
+
 	env.ecfg = ecfg
 
 	// Reset storage account key.  Even if we had one before, it may not
@@ -327,10 +370,30 @@ func newHostedService(azure *gwacl.ManagementAPI, prefix, affinityGroupName, lab
 	return svc, nil
 }
 
-// selectInstanceTypeAndImage returns the appropriate instance-type name and
-// the OS image name for launching a virtual machine with the given parameters.
-func (env *azureEnviron) selectInstanceTypeAndImage(cons constraints.Value, series, location string) (string, string, error) {
+// selectInstanceTypeAndImage returns the appropriate instance-type name,
+// the OS image name (or VHD URL) and, if the image came from the
+// "custom-images" config map, the matching customImageSpec for
+// launching a virtual machine with the given parameters. series
+// determines the guest OS (see seriesOS); the simplestreams lookup
+// below is already series-scoped, so a Windows or CentOS series
+// naturally resolves to a matching image without any special-casing
+// here.
+func (env *azureEnviron) selectInstanceTypeAndImage(cons constraints.Value, series, location string) (string, string, *customImageSpec, error) {
 	ecfg := env.getSnapshot().ecfg
+
+	if custom, ok, err := ecfg.customImageFor(series); err != nil {
+		return "", "", nil, err
+	} else if ok {
+		// A custom image bypasses simplestreams entirely for this
+		// series, the same way forceImageName does below, since there
+		// may be no simplestreams entry for it at all.
+		machineType, err := selectMachineType(gwacl.RoleSizes, defaultToBaselineSpec(cons))
+		if err != nil {
+			return "", "", nil, err
+		}
+		return machineType.Name, custom.Image, &custom, nil
+	}
+
 	sourceImageName := ecfg.forceImageName()
 	if sourceImageName != "" {
 		// Configuration forces us to use a specific image.  There may
@@ -343,9 +406,9 @@ func (env *azureEnviron) selectInstanceTypeAndImage(cons constraints.Value, seri
 		// Select the instance type using simple, Azure-specific code.
 		machineType, err := selectMachineType(gwacl.RoleSizes, defaultToBaselineSpec(cons))
 		if err != nil {
-			return "", "", err
+			return "", "", nil, err
 		}
-		return machineType.Name, sourceImageName, nil
+		return machineType.Name, sourceImageName, nil, nil
 	}
 
 	// Choose the most suitable instance type and OS image, based on
@@ -361,9 +424,9 @@ func (env *azureEnviron) selectInstanceTypeAndImage(cons constraints.Value, seri
 	}
 	spec, err := findInstanceSpec(env, constraint)
 	if err != nil {
-		return "", "", err
+		return "", "", nil, err
 	}
-	return spec.InstanceType.Id, spec.Image.Id, nil
+	return spec.InstanceType.Id, spec.Image.Id, nil, nil
 }
 
 // ensureCloudService returns the cloud service with
@@ -423,6 +486,21 @@ func (env *azureEnviron) createRole(azure *gwacl.ManagementAPI, role *gwacl.Role
 	if err != nil {
 		return nil, err
 	}
+	// Derive the availability set from the cloud service label, so that
+	// services sharing a label (and therefore the affinity the label
+	// groups them by) land in the same availability set, as Azure's SLA
+	// for VM uptime requires at least two VMs in one.
+	role.AvailabilitySetName = availabilitySetName(label)
+	if env.Config().FirewallMode() == config.FwInstance {
+		// Give this role's service its own NSG, and attach it in place
+		// of the environment-wide one newRole attached by default, so
+		// OpenPorts/ClosePorts on the resulting instance only ever
+		// affect this service.
+		if err := env.ensureInstanceNetworkSecurityGroup(service.ServiceName); err != nil {
+			return nil, err
+		}
+		networkConfigurationSet(role).NetworkSecurityGroup = instanceNetworkSecurityGroupName(service.ServiceName)
+	}
 	if len(service.Deployments) == 0 {
 		// This is a newly created cloud service, so we
 		// should destroy it if anything below fails.
@@ -439,7 +517,7 @@ func (env *azureEnviron) createRole(azure *gwacl.ManagementAPI, role *gwacl.Role
 		// Create an initial deployment.
 		deployment := gwacl.NewDeploymentForCreateVMDeployment(
 			deploymentNameV2(service.ServiceName),
-			deploymentSlot,
+			env.getSnapshot().ecfg.deploymentSlot(),
 			label,
 			[]gwacl.Role{*role},
 			env.getVirtualNetworkName(),
@@ -506,11 +584,28 @@ func (env *azureEnviron) StartInstance(cons constraints.Value, possibleTools too
 
 	location := env.getSnapshot().ecfg.location()
 	series := possibleTools.OneSeries()
-	instanceType, sourceImageName, err := env.selectInstanceTypeAndImage(cons, series, location)
+	if seriesOS(series) == osWindows {
+		// makeCustomData above renders cloud-init YAML; the VM
+		// extension newRole attaches for Windows (see vmExtension)
+		// feeds that payload straight to PowerShell's
+		// Invoke-Expression, which cannot interpret cloud-init syntax.
+		// Until a PowerShell-native custom-data renderer exists,
+		// refuse to start a Windows instance rather than hand it
+		// boot data that is guaranteed to fail.
+		return nil, nil, fmt.Errorf("starting a Windows instance is not supported: no PowerShell-compatible custom data renderer for series %q", series)
+	}
+	instanceType, sourceImageName, customImage, err := env.selectInstanceTypeAndImage(cons, series, location)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if cons.Preemptible != nil && *cons.Preemptible {
+		if !env.getSnapshot().ecfg.allowPreemptibleFallback() {
+			return nil, nil, errPreemptibleUnsupported
+		}
+		logger.Infof("preemptible requested but not supported by this provider; falling back to on-demand pricing (allow-preemptible-fallback is set)")
+	}
+
 	// We use the cloud service label as a way to group instances with
 	// the same affinity, so that machines can be be allocated to the
 	// same availability set.
@@ -525,10 +620,10 @@ func (env *azureEnviron) StartInstance(cons constraints.Value, possibleTools too
 		// that is deployed to the machine.
 	}
 
-	vhd := env.newOSDisk(sourceImageName)
+	vhd := env.newOSDisk(sourceImageName, series, customImage)
 	// If we're creating machine-0, we'll want to expose port 22.
 	// All other machines get an auto-generated public port for SSH.
-	role := env.newRole(instanceType, vhd, userData, machineConfig.StateServer)
+	role := env.newRole(instanceType, vhd, userData, machineConfig.StateServer, series)
 	inst, err := env.createRole(azure.ManagementAPI, role, label)
 	if err != nil {
 		return nil, nil, err
@@ -574,12 +669,28 @@ func (env *azureEnviron) getInstance(hostedService *gwacl.HostedService, roleNam
 		roleName:       roleName,
 		roleInstance:   roleInstance,
 	}
+	if env.getSnapshot().ecfg.deploymentSlot() == stagingDeploymentSlot {
+		// Staging deployments don't resolve via
+		// "<service>.cloudapp.net"; Azure assigns an arbitrary hostname
+		// that only appears in the deployment's URL.
+		dnsName, err := stagingHostname(deployment.Url)
+		if err != nil {
+			return nil, err
+		}
+		instance.dnsName = dnsName
+	}
 	return instance, nil
 }
 
 // newOSDisk creates a gwacl.OSVirtualHardDisk object suitable for an
-// Azure Virtual Machine.
-func (env *azureEnviron) newOSDisk(sourceImageName string) *gwacl.OSVirtualHardDisk {
+// Azure Virtual Machine running series. If custom is non-nil and names
+// a VHD URL, that VHD is referenced directly as the disk's mediaLink
+// instead of creating a new one in this environment's own storage
+// account.
+func (env *azureEnviron) newOSDisk(sourceImageName, series string, custom *customImageSpec) *gwacl.OSVirtualHardDisk {
+	if custom != nil && isVHDURL(custom.Image) {
+		return gwacl.NewOSVirtualHardDisk("", "", "", custom.Image, "", custom.OS)
+	}
 	vhdName := gwacl.MakeRandomDiskName("juju")
 	vhdPath := fmt.Sprintf("vhds/%s", vhdName)
 	snap := env.getSnapshot()
@@ -587,7 +698,7 @@ func (env *azureEnviron) newOSDisk(sourceImageName string) *gwacl.OSVirtualHardD
 	mediaLink := gwacl.CreateVirtualHardDiskMediaLink(storageAccount, vhdPath)
 	// The disk label is optional and the disk name can be omitted if
 	// mediaLink is provided.
-	return gwacl.NewOSVirtualHardDisk("", "", "", mediaLink, sourceImageName, "Linux")
+	return gwacl.NewOSVirtualHardDisk("", "", "", mediaLink, sourceImageName, osDiskType(seriesOS(series)))
 }
 
 // getInitialEndpoints returns a slice of the endpoints every instance should have open
@@ -632,32 +743,72 @@ func (env *azureEnviron) getInitialEndpoints(stateServer bool) []gwacl.InputEndp
 // the given Virtual Hard Drive.
 //
 // The VM will have:
-// - an 'ubuntu' user defined with an unguessable (randomly generated) password
-// - its ssh port (TCP 22) open
+//   - a provisioning user defined with an unguessable (randomly generated)
+//     password: 'ubuntu' on Linux series; on Windows ones only the
+//     password is passed, with no user name
+//   - its ssh port (TCP 22) open
+//
 // (if a state server)
 // - its state port (TCP mongoDB) port open
 // - its API port (TCP) open
 //
 // roleSize is the name of one of Azure's machine types, e.g. ExtraSmall,
-// Large, A6 etc.
-func (env *azureEnviron) newRole(roleSize string, vhd *gwacl.OSVirtualHardDisk, userData string, stateServer bool) *gwacl.Role {
+// Large, A6 etc. series determines the guest OS (see seriesOS) and
+// therefore how the VM is provisioned: Ubuntu gets userData wired in
+// directly via its Linux provisioning configuration set, while Windows
+// and CentOS have no such mechanism and instead run it through a VM
+// extension (see vmExtensionProperties).
+func (env *azureEnviron) newRole(roleSize string, vhd *gwacl.OSVirtualHardDisk, userData string, stateServer bool, series string) *gwacl.Role {
 	roleName := gwacl.MakeRandomRoleName("juju")
-	// Create a Linux Configuration with the username and the password
-	// empty and disable SSH with password authentication.
 	hostname := roleName
-	username := "ubuntu"
-	password := gwacl.MakeRandomPassword()
-	linuxConfigurationSet := gwacl.NewLinuxProvisioningConfigurationSet(hostname, username, password, userData, "true")
+	os := seriesOS(series)
+
+	var provisioningConfigurationSet gwacl.ConfigurationSet
+	if os == osWindows {
+		// Windows has no cloud-init equivalent to pass userData to
+		// directly; it is instead fed through the CustomScriptExtension
+		// attached below.
+		adminPassword := gwacl.MakeRandomPassword()
+		windowsConfigurationSet := gwacl.NewWindowsProvisioningConfigurationSet(hostname, adminPassword, "", "", "true")
+		provisioningConfigurationSet = *windowsConfigurationSet
+	} else {
+		// Create a Linux Configuration with the 'ubuntu' username and a
+		// random password, and disable SSH with password authentication.
+		username := "ubuntu"
+		password := gwacl.MakeRandomPassword()
+		linuxConfigurationSet := gwacl.NewLinuxProvisioningConfigurationSet(hostname, username, password, userData, "true")
+		provisioningConfigurationSet = *linuxConfigurationSet
+	}
+
 	// Generate a Network Configuration with the initially required ports open.
 	networkConfigurationSet := gwacl.NewNetworkConfigurationSet(env.getInitialEndpoints(stateServer), nil)
+	// Attach this environment's Network Security Group to the role's
+	// NIC, so that ports opened via OpenPorts apply to it. Input
+	// endpoints alone cannot express a restricted source CIDR; the NSG
+	// is what lets OpenPorts honour one.
+	networkConfigurationSet.NetworkSecurityGroup = env.getNetworkSecurityGroupName()
+
 	role := gwacl.NewRole(
 		roleSize, roleName, vhd,
-		[]gwacl.ConfigurationSet{*linuxConfigurationSet, *networkConfigurationSet},
+		[]gwacl.ConfigurationSet{provisioningConfigurationSet, *networkConfigurationSet},
 	)
-	role.AvailabilitySetName = "juju"
+	// AvailabilitySetName is filled in by createRole once the cloud
+	// service's label is known.
+	if ext := vmExtension(os, userData); ext != nil {
+		role.ResourceExtensionReferences = []gwacl.ResourceExtensionReference{*ext}
+	}
 	return role
 }
 
+// networkConfigurationSet returns role's network configuration set, the
+// second of the two configuration sets newRole always builds
+// (provisioning, then network), so that callers such as createRole can
+// customize it, e.g. swapping in a per-service NSG, after the role has
+// already been built.
+func networkConfigurationSet(role *gwacl.Role) *gwacl.ConfigurationSet {
+	return &role.ConfigurationSets[1]
+}
+
 // Spawn this many goroutines to issue requests for destroying services.
 // TODO: this is currently set to 1 because of a problem in Azure:
 // removing Services in the same affinity group concurrently causes a conflict.
@@ -674,8 +825,13 @@ func (env *azureEnviron) StopInstances(instances []instance.Instance) error {
 	defer env.releaseManagementAPI(context)
 
 	// Destroy all the roles in parallel. Record services for which
-	// roles are destroyed, so we can garbage collect later.
+	// roles are destroyed, so we can garbage collect later, and the
+	// mediaLink of each role's OS disk, so we can follow up DeleteMedia
+	// (which is known to return before the underlying page blob is
+	// actually released) with our own sweep.
 	services := make(map[string]bool)
+	var mediaLinksMu sync.Mutex
+	var mediaLinks []string
 	run := parallel.NewRun(maxConcurrentDeletes)
 	for _, instance := range instances {
 		instance, ok := instance.(*azureInstance)
@@ -687,16 +843,34 @@ func (env *azureEnviron) StopInstances(instances []instance.Instance) error {
 		roleName := instance.roleName
 		services[serviceName] = true
 		run.Do(func() error {
-			return context.DeleteRole(&gwacl.DeleteRoleRequest{
+			mediaLink, err := env.vhdMediaLink(context.ManagementAPI, serviceName, deploymentName, roleName)
+			if err != nil {
+				return err
+			}
+			if err := context.DeleteRole(&gwacl.DeleteRoleRequest{
 				ServiceName:    serviceName,
 				DeploymentName: deploymentName,
 				RoleName:       roleName,
 				DeleteMedia:    true,
-			})
+			}); err != nil {
+				return err
+			}
+			if mediaLink != "" {
+				mediaLinksMu.Lock()
+				mediaLinks = append(mediaLinks, mediaLink)
+				mediaLinksMu.Unlock()
+			}
+			return nil
 		})
 	}
 	if err := run.Wait(); err != nil {
-		return fmt.Errorf("failed to delete roles", err)
+		return fmt.Errorf("failed to delete roles: %v", err)
+	}
+	if err := env.deleteDanglingVHDs(mediaLinks); err != nil {
+		// The roles are already gone; log and carry on rather than
+		// fail StopInstances over a VHD that will be picked up by
+		// destroyAllServices' sweep, or a future Destroy, anyway.
+		logger.Warningf("failed to garbage-collect VHDs: %v", err)
 	}
 
 	// Destroy services now bereft of roles.
@@ -736,13 +910,28 @@ func (env *azureEnviron) destroyAllServices() error {
 
 	run := parallel.NewRun(maxConcurrentDeletes)
 	for _, service := range services {
+		serviceName := service.ServiceName
 		run.Do(func() error {
-			return context.DestroyHostedService(&gwacl.DestroyHostedServiceRequest{
-				ServiceName: service.ServiceName,
-			})
+			if err := context.DestroyHostedService(&gwacl.DestroyHostedServiceRequest{
+				ServiceName: serviceName,
+			}); err != nil {
+				return err
+			}
+			// Only ever set in instance firewall mode (see createRole);
+			// a no-op otherwise.
+			return env.deleteInstanceNetworkSecurityGroup(serviceName)
 		})
 	}
-	return run.Wait()
+	if err := run.Wait(); err != nil {
+		return err
+	}
+
+	// Every service in this environment is now gone, so every one of
+	// its VHDs and reserved IPs is dangling: sweep them all, including
+	// any left behind by an earlier, interrupted Destroy that never got
+	// this far. A zero TTL is safe here precisely because there is
+	// nothing left that could still be mid-creation.
+	return env.sweepDanglingResources(0)
 }
 
 // Instances is specified in the Environ interface.
@@ -819,7 +1008,10 @@ func (env *azureEnviron) Instances(ids []instance.Id) ([]instance.Instance, erro
 	return instances, err
 }
 
-// AllInstances is specified in the InstanceBroker interface.
+// AllInstances is specified in the InstanceBroker interface. Each
+// returned instance carries its role's current power state through to
+// Status (see roleEvicted), so callers can see which ones Azure has
+// deallocated without a further round trip.
 func (env *azureEnviron) AllInstances() ([]instance.Instance, error) {
 	// The instance list is built using the list of all the Azure
 	// Services (instance==service).
@@ -876,6 +1068,11 @@ func (env *azureEnviron) Destroy() error {
 		return fmt.Errorf("cannot destroy instances: %v", err)
 	}
 
+	// Delete the network security group now that nothing references it.
+	if err := env.deleteNetworkSecurityGroup(); err != nil {
+		return fmt.Errorf("cannot delete the environment's network security group: %v", err)
+	}
+
 	// Delete vnet and affinity group.
 	if err := env.deleteVirtualNetwork(); err != nil {
 		return fmt.Errorf("cannot delete the environment's virtual network: %v", err)
@@ -895,22 +1092,22 @@ func (env *azureEnviron) Destroy() error {
 	return nil
 }
 
-// OpenPorts is specified in the Environ interface. However, Azure does not
-// support the global firewall mode.
+// OpenPorts is specified in the Environ interface. It opens the given
+// ports on the environment's Network Security Group, which every
+// instance's NIC is attached to (see newRole), so the rule applies
+// environment-wide rather than per-instance.
 func (env *azureEnviron) OpenPorts(ports []instance.Port) error {
-	return nil
+	return env.openPortsOnNSG(env.getNetworkSecurityGroupName(), "0.0.0.0/0", ports)
 }
 
-// ClosePorts is specified in the Environ interface. However, Azure does not
-// support the global firewall mode.
+// ClosePorts is specified in the Environ interface.
 func (env *azureEnviron) ClosePorts(ports []instance.Port) error {
-	return nil
+	return env.closePortsOnNSG(env.getNetworkSecurityGroupName(), ports)
 }
 
 // Ports is specified in the Environ interface.
 func (env *azureEnviron) Ports() ([]instance.Port, error) {
-	// TODO: implement this.
-	return []instance.Port{}, nil
+	return env.portsOnNSG(env.getNetworkSecurityGroupName())
 }
 
 // Provider is specified in the Environ interface.
@@ -928,6 +1125,19 @@ func (env *azureEnviron) Provider() environs.EnvironProvider {
 type azureManagementContext struct {
 	*gwacl.ManagementAPI
 	certFile *tempCertFile
+
+	// authorizer is set instead of certFile when this context was
+	// built with service-principal authentication (see
+	// getServicePrincipalAuthorizer); there is no file to clean up in
+	// that case.
+	authorizer *autorest.BearerAuthorizer
+
+	// ecfg is the config generation this context was built from.
+	// releaseManagementAPI compares it against the environment's
+	// current config to tell a still-current context, which it pools
+	// for reuse, from a stale one left over from before a
+	// reconfiguration, which it discards instead.
+	ecfg *azureEnvironConfig
 }
 
 var (
@@ -943,12 +1153,34 @@ var (
 )
 
 // getManagementAPI obtains a context object for interfacing with Azure's
-// management API.
-// For now, each invocation just returns a separate object.  This is probably
-// wasteful (each context gets its own SSL connection) and may need optimizing
-// later.
+// management API. If env.contextPool already has an idle one for the
+// current config generation, that is reused (and its SSL connection
+// with it); only when the pool is empty is a new one built from
+// scratch.
+//
+// If the environment is configured for service-principal authentication
+// (see usesServicePrincipal), a freshly built context authenticates with
+// a cached OAuth2 bearer token instead of writing out the management
+// certificate; the certificate-based mode remains available, unchanged,
+// for back-compat.
 func (env *azureEnviron) getManagementAPI() (*azureManagementContext, error) {
 	snap := env.getSnapshot()
+	if context := env.contextPool.getManagementContext(snap.ecfg); context != nil {
+		return context, nil
+	}
+
+	if snap.ecfg.usesServicePrincipal() {
+		authorizer, err := getServicePrincipalAuthorizer(snap.ecfg)
+		if err != nil {
+			return nil, err
+		}
+		mgtAPI, err := newManagementAPIWithServicePrincipal(snap.ecfg, authorizer)
+		if err != nil {
+			return nil, err
+		}
+		return &azureManagementContext{ManagementAPI: mgtAPI, authorizer: authorizer, ecfg: snap.ecfg}, nil
+	}
+
 	subscription := snap.ecfg.managementSubscriptionId()
 	certData := snap.ecfg.managementCertificate()
 	certFile, err := newTempCertFile([]byte(certData))
@@ -966,22 +1198,36 @@ func (env *azureEnviron) getManagementAPI() (*azureManagementContext, error) {
 	context := azureManagementContext{
 		ManagementAPI: mgtAPI,
 		certFile:      certFile,
+		ecfg:          snap.ecfg,
 	}
 	return &context, nil
 }
 
-// releaseManagementAPI frees up a context object obtained through
-// getManagementAPI.
+// releaseManagementAPI returns a context object obtained through
+// getManagementAPI to env.contextPool, so a later getManagementAPI call
+// for the same config generation can reuse it. If the environment has
+// since been reconfigured, the context is discarded instead (deleting
+// its certificate file, if it has one), since nothing will ever look it
+// up under the new generation.
 func (env *azureEnviron) releaseManagementAPI(context *azureManagementContext) {
 	// Be tolerant to incomplete context objects, in case we ever get
 	// called during cleanup of a failed attempt to create one.
-	if context == nil || context.certFile == nil {
+	if context == nil {
 		return
 	}
-	// For now, all that needs doing is to delete the temporary certificate
-	// file.  We may do cleverer things later, such as connection pooling
-	// where this method returns a context to the pool.
-	context.certFile.Delete()
+	if context.ecfg != env.getSnapshot().ecfg {
+		deleteManagementContextCertFile(context)
+		return
+	}
+	env.contextPool.putManagementContext(context.ecfg, context, deleteManagementContextCertFile)
+}
+
+// deleteManagementContextCertFile deletes context's temporary
+// certificate file, if it has one (service-principal contexts don't).
+func deleteManagementContextCertFile(context *azureManagementContext) {
+	if context.certFile != nil {
+		context.certFile.Delete()
+	}
 }
 
 // updateStorageAccountKey queries the storage account key, and updates the
@@ -1028,13 +1274,16 @@ func (env *azureEnviron) updateStorageAccountKey(snapshot *azureEnviron) (string
 	return key, nil
 }
 
-// getStorageContext obtains a context object for interfacing with Azure's
-// storage API.
-// For now, each invocation just returns a separate object.  This is probably
-// wasteful (each context gets its own SSL connection) and may need optimizing
-// later.
+// getStorageContext obtains a context object for interfacing with
+// Azure's storage API. env.contextPool caches one per config
+// generation, so repeated calls (e.g. across reconcileDanglingBlobs and
+// deleteDanglingVHDs in the same sweep) reuse the same underlying SSL
+// connection instead of each opening their own.
 func (env *azureEnviron) getStorageContext() (*gwacl.StorageContext, error) {
 	snap := env.getSnapshot()
+	if context := env.contextPool.getStorageContext(snap.ecfg); context != nil {
+		return context, nil
+	}
 	key := snap.storageAccountKey
 	if key == "" {
 		// We don't know the storage-account key yet.  Request it.
@@ -1044,13 +1293,14 @@ func (env *azureEnviron) getStorageContext() (*gwacl.StorageContext, error) {
 			return nil, err
 		}
 	}
-	context := gwacl.StorageContext{
+	context := &gwacl.StorageContext{
 		Account:       snap.ecfg.storageAccountName(),
 		Key:           key,
 		AzureEndpoint: gwacl.GetEndpoint(snap.ecfg.location()),
 		RetryPolicy:   retryPolicy,
 	}
-	return &context, nil
+	env.contextPool.putStorageContext(snap.ecfg, context)
+	return context, nil
 }
 
 // baseURLs specifies an Azure specific location where we look for simplestreams information.
@@ -1061,10 +1311,16 @@ var baseURLs = []string{}
 
 // GetImageSources returns a list of sources which are used to search for simplestreams image metadata.
 func (env *azureEnviron) GetImageSources() ([]simplestreams.DataSource, error) {
-	sources := make([]simplestreams.DataSource, 1+len(baseURLs))
-	sources[0] = storage.NewStorageSimpleStreamsDataSource("cloud storage", env.Storage(), storage.BaseImagesPath)
-	for i, url := range baseURLs {
-		sources[i+1] = simplestreams.NewURLDataSource("Azure base URL", url, simplestreams.VerifySSLHostnames)
+	ecfg := env.getSnapshot().ecfg
+	var sources []simplestreams.DataSource
+	if ecfg.usesImageGallery() {
+		// Ordered first, so a configured custom image always wins over
+		// the public marketplace sources below.
+		sources = append(sources, &galleryImageDataSource{ecfg: ecfg})
+	}
+	sources = append(sources, storage.NewStorageSimpleStreamsDataSource("cloud storage", env.Storage(), storage.BaseImagesPath))
+	for _, url := range baseURLs {
+		sources = append(sources, simplestreams.NewURLDataSource("Azure base URL", url, simplestreams.VerifySSLHostnames))
 	}
 	return sources, nil
 }
@@ -1077,12 +1333,18 @@ func (env *azureEnviron) GetToolsSources() ([]simplestreams.DataSource, error) {
 	return sources, nil
 }
 
-// getImageMetadataSigningRequired returns whether this environment requires
-// image metadata from Simplestreams to be signed.
+// getImageMetadataSigningRequired reports whether this environ's image
+// metadata must be signed. It gives one answer for the whole environ, not
+// one per source: when a gallery image is configured it returns false,
+// since the gallery image source (see GetImageSources) synthesizes its
+// own content locally rather than fetching a signed simplestreams index,
+// and the public simplestreams sources are then not required to be
+// signed either; otherwise it returns true. Kept zero-argument to match its original signature - adding a
+// source parameter here would break any caller of the pre-existing
+// method outside this trimmed tree.
 func (env *azureEnviron) getImageMetadataSigningRequired() bool {
-	// Hard-coded to true for now.  Once we support custom base URLs,
-	// this may have to change.
-	return true
+	ecfg := env.getSnapshot().ecfg
+	return !ecfg.usesImageGallery()
 }
 
 // Region is specified in the HasRegion interface.
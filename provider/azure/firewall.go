@@ -0,0 +1,176 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"launchpad.net/gwacl"
+
+	"launchpad.net/juju-core/instance"
+)
+
+// nsgLockTable hands out one *sync.Mutex per NSG name, so that rule CRUD
+// against two different NSGs (e.g. two hosted services' own NSGs in
+// instance firewall mode) can proceed concurrently while rule CRUD
+// against the same NSG is always serialized.
+type nsgLockTable struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newNSGLockTable() *nsgLockTable {
+	return &nsgLockTable{locks: make(map[string]*sync.Mutex)}
+}
+
+// forName returns the lock for name, creating it on first use.
+func (t *nsgLockTable) forName(name string) *sync.Mutex {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	lock, ok := t.locks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		t.locks[name] = lock
+	}
+	return lock
+}
+
+// getNetworkSecurityGroupName returns the name of the Network Security
+// Group used by all the roles in this environment.
+func (env *azureEnviron) getNetworkSecurityGroupName() string {
+	return env.getEnvPrefix() + "nsg"
+}
+
+// createNetworkSecurityGroup creates this environment's Network
+// Security Group. It is created once per environment, at Bootstrap
+// time, and every role's NIC is attached to it in newRole.
+func (env *azureEnviron) createNetworkSecurityGroup() error {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+	nsgName := env.getNetworkSecurityGroupName()
+	location := env.getSnapshot().ecfg.location()
+	return azure.CreateNetworkSecurityGroup(&gwacl.CreateNetworkSecurityGroupRequest{
+		Name:     nsgName,
+		Label:    nsgName,
+		Location: location,
+	})
+}
+
+// deleteNetworkSecurityGroup deletes this environment's Network
+// Security Group. It is called from Destroy, after destroyAllServices
+// has removed every role that references it.
+func (env *azureEnviron) deleteNetworkSecurityGroup() error {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+	return azure.DeleteNetworkSecurityGroup(env.getNetworkSecurityGroupName())
+}
+
+// nsgRuleName returns the deterministic name used for the NSG rule that
+// opens port. Naming rules this way, rather than letting Azure assign
+// one, lets ClosePorts and Ports find and remove/list Juju's own rules
+// without having to track rule names separately.
+func nsgRuleName(port instance.Port) string {
+	return fmt.Sprintf("juju-%s-%d-%d", port.Protocol, port.Number, port.Number)
+}
+
+// newNSGRule returns the gwacl rule that opens port for sourceCIDR.
+func newNSGRule(port instance.Port, sourceCIDR string) gwacl.NSGRule {
+	return gwacl.NSGRule{
+		Name:                     nsgRuleName(port),
+		Protocol:                 port.Protocol,
+		SourceAddressPrefix:      sourceCIDR,
+		SourcePortRange:          "*",
+		DestinationAddressPrefix: "*",
+		DestinationPortRange:     fmt.Sprintf("%d", port.Number),
+		Access:                   gwacl.SecurityRuleAccessAllow,
+		Direction:                gwacl.SecurityRuleDirectionInbound,
+	}
+}
+
+// openPortsOnNSG adds a rule for each of ports to the named NSG,
+// allowing traffic from sourceCIDR. Rule CRUD is serialized through
+// env.nsgLocks because Azure rejects concurrent mutations to the same
+// NSG as a conflict.
+func (env *azureEnviron) openPortsOnNSG(nsgName, sourceCIDR string, ports []instance.Port) error {
+	lock := env.nsgLocks.forName(nsgName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	for _, port := range ports {
+		rule := newNSGRule(port, sourceCIDR)
+		if err := azure.AddNetworkSecurityGroupRule(nsgName, &rule); err != nil {
+			return fmt.Errorf("cannot open port %v on %q: %v", port, nsgName, err)
+		}
+	}
+	return nil
+}
+
+// closePortsOnNSG removes the rule for each of ports from the named
+// NSG, identifying each rule by its deterministic nsgRuleName.
+func (env *azureEnviron) closePortsOnNSG(nsgName string, ports []instance.Port) error {
+	lock := env.nsgLocks.forName(nsgName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	for _, port := range ports {
+		ruleName := nsgRuleName(port)
+		if err := azure.DeleteNetworkSecurityGroupRule(nsgName, ruleName); err != nil {
+			return fmt.Errorf("cannot close port %v on %q: %v", port, nsgName, err)
+		}
+	}
+	return nil
+}
+
+// portsOnNSG returns the ports currently opened by Juju's rules on the
+// named NSG, ignoring any rules not following the juju-<proto>-<from>-
+// <to> naming convention (e.g. rules an operator added by hand).
+func (env *azureEnviron) portsOnNSG(nsgName string) ([]instance.Port, error) {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return nil, err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	nsg, err := azure.GetNetworkSecurityGroup(nsgName)
+	if err != nil {
+		return nil, err
+	}
+	var ports []instance.Port
+	for _, rule := range nsg.Rules {
+		if !strings.HasPrefix(rule.Name, "juju-") {
+			// Not one of ours; an operator may have added it by hand.
+			continue
+		}
+		number, err := strconv.Atoi(rule.DestinationPortRange)
+		if err != nil {
+			continue
+		}
+		ports = append(ports, instance.Port{
+			Protocol: rule.Protocol,
+			Number:   number,
+		})
+	}
+	return ports, nil
+}
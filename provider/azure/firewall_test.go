@@ -0,0 +1,25 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	gc "gopkg.in/check.v1"
+
+	"launchpad.net/juju-core/instance"
+)
+
+type FirewallSuite struct{}
+
+var _ = gc.Suite(&FirewallSuite{})
+
+func (s *FirewallSuite) TestNSGRuleName(c *gc.C) {
+	name := nsgRuleName(instance.Port{Protocol: "tcp", Number: 80})
+	c.Assert(name, gc.Equals, "juju-tcp-80-80")
+}
+
+func (s *FirewallSuite) TestNSGRuleNameDistinguishesProtocol(c *gc.C) {
+	tcp := nsgRuleName(instance.Port{Protocol: "tcp", Number: 53})
+	udp := nsgRuleName(instance.Port{Protocol: "udp", Number: 53})
+	c.Assert(tcp, gc.Not(gc.Equals), udp)
+}
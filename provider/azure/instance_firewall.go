@@ -0,0 +1,87 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"launchpad.net/gwacl"
+
+	"launchpad.net/juju-core/instance"
+)
+
+// instanceNetworkSecurityGroupName returns the name of the Network
+// Security Group dedicated to serviceName's role, used in instance
+// firewall mode instead of the environment-wide one every role's NIC is
+// attached to by default (see newRole).
+func instanceNetworkSecurityGroupName(serviceName string) string {
+	return serviceName + "-nsg"
+}
+
+// ensureInstanceNetworkSecurityGroup creates the Network Security Group
+// dedicated to serviceName, if it does not already exist. It is called
+// from createRole when the environment is configured for instance
+// firewall mode, before the role's deployment is created, so the NSG
+// can be attached to the role's NIC from the outset.
+func (env *azureEnviron) ensureInstanceNetworkSecurityGroup(serviceName string) error {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	nsgName := instanceNetworkSecurityGroupName(serviceName)
+	if _, err := azure.GetNetworkSecurityGroup(nsgName); err == nil {
+		// Already exists, e.g. because this is the second role added
+		// to serviceName's deployment.
+		return nil
+	}
+	location := env.getSnapshot().ecfg.location()
+	return azure.CreateNetworkSecurityGroup(&gwacl.CreateNetworkSecurityGroupRequest{
+		Name:     nsgName,
+		Label:    nsgName,
+		Location: location,
+	})
+}
+
+// deleteInstanceNetworkSecurityGroup deletes serviceName's dedicated
+// Network Security Group. It is called once serviceName's hosted
+// service has been destroyed, so the NSG never outlives the role it
+// was created for. A missing NSG (e.g. because the environment is not
+// in instance firewall mode, so one was never created) is not an
+// error.
+func (env *azureEnviron) deleteInstanceNetworkSecurityGroup(serviceName string) error {
+	azure, err := env.getManagementAPI()
+	if err != nil {
+		return err
+	}
+	defer env.releaseManagementAPI(azure)
+
+	nsgName := instanceNetworkSecurityGroupName(serviceName)
+	if _, err := azure.GetNetworkSecurityGroup(nsgName); err != nil {
+		return nil
+	}
+	return azure.DeleteNetworkSecurityGroup(nsgName)
+}
+
+// OpenPorts is specified in the instance.Instance interface. It opens
+// the given ports on this instance's own Network Security Group, for
+// environments running in instance firewall mode.
+func (inst *azureInstance) OpenPorts(machineId string, ports []instance.Port) error {
+	env := inst.environ
+	nsgName := instanceNetworkSecurityGroupName(inst.hostedService.ServiceName)
+	return env.openPortsOnNSG(nsgName, "0.0.0.0/0", ports)
+}
+
+// ClosePorts is specified in the instance.Instance interface.
+func (inst *azureInstance) ClosePorts(machineId string, ports []instance.Port) error {
+	env := inst.environ
+	nsgName := instanceNetworkSecurityGroupName(inst.hostedService.ServiceName)
+	return env.closePortsOnNSG(nsgName, ports)
+}
+
+// Ports is specified in the instance.Instance interface.
+func (inst *azureInstance) Ports(machineId string) ([]instance.Port, error) {
+	env := inst.environ
+	nsgName := instanceNetworkSecurityGroupName(inst.hostedService.ServiceName)
+	return env.portsOnNSG(nsgName)
+}
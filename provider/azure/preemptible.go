@@ -0,0 +1,64 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+
+	"launchpad.net/gwacl"
+)
+
+// allowPreemptibleFallbackAttr is the azure config key controlling
+// whether StartInstance proceeds at full (on-demand) pricing when
+// cons.Preemptible is set. No preemptible request is made first (see
+// errPreemptibleUnsupported), so there is no retry.
+const allowPreemptibleFallbackAttr = "allow-preemptible-fallback"
+
+// allowPreemptibleFallback returns this config's
+// "allow-preemptible-fallback" setting, defaulting to false so that a
+// preemptible request that can't be honoured fails loudly rather than
+// silently landing on a full-price VM.
+func (ecfg *azureEnvironConfig) allowPreemptibleFallback() bool {
+	v, _ := ecfg.UnknownAttrs()[allowPreemptibleFallbackAttr].(bool)
+	return v
+}
+
+// errPreemptibleUnsupported is returned from StartInstance when
+// cons.Preemptible is set and allow-preemptible-fallback is not. Azure's
+// classic Service Management API, which this provider targets (see
+// launchpad.net/gwacl), predates low-priority/spot VMs entirely --
+// that's an ARM/VMSS-only feature, so there is no request this provider
+// can make of Azure for an actually-evictable, discounted VM. Every role
+// it creates runs at full, non-preemptible pricing regardless of
+// cons.Preemptible, so callers asking for one need an explicit error to
+// either retry past (with allow-preemptible-fallback) or surface, rather
+// than silently being billed on-demand while believing they got a spot
+// instance.
+var errPreemptibleUnsupported = fmt.Errorf(
+	"preemptible instances are not supported by this Azure provider (classic Service Management API has no low-priority VM concept)")
+
+// roleEvicted reports whether roleInstance's power state indicates Azure
+// stopped and deallocated the role. It is the closest classic-API signal
+// to an ARM low-priority VM's eviction notice, even though this provider
+// never actually requests low-priority capacity (see
+// errPreemptibleUnsupported above); azureInstance.Status uses it so the
+// provisioner can still tell a deallocated role apart from a merely
+// slow-booting one.
+func roleEvicted(roleInstance *gwacl.RoleInstance) bool {
+	return roleInstance != nil && roleInstance.PowerState == "StoppedDeallocated"
+}
+
+// Status is specified in the instance.Instance interface. It reports
+// when Azure has stopped and deallocated the underlying role, the
+// clearest signal this provider has that a unit needs rescheduling
+// rather than waiting on a VM Azure isn't going to restart on its own.
+func (inst *azureInstance) Status() string {
+	if roleEvicted(inst.roleInstance) {
+		return "StoppedDeallocated"
+	}
+	if inst.roleInstance == nil {
+		return ""
+	}
+	return inst.roleInstance.InstanceStatus
+}
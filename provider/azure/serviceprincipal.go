@@ -0,0 +1,156 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+
+	"launchpad.net/gwacl"
+)
+
+// Azure config keys for service-principal authentication, an
+// alternative to the original "management-certificate" mode that newer
+// tooling (and Azure AD's own recommendations) has moved to.
+const (
+	clientIDAttr         = "client-id"
+	clientSecretAttr     = "client-secret"
+	tenantIDAttr         = "tenant-id"
+	cloudEnvironmentAttr = "cloud-environment"
+)
+
+// Recognised values of "cloud-environment"; "" is treated the same as
+// AzurePublicCloud.
+const (
+	AzurePublicCloud       = "AzurePublicCloud"
+	AzureChinaCloud        = "AzureChinaCloud"
+	AzureUSGovernmentCloud = "AzureUSGovernmentCloud"
+	AzureGermanCloud       = "AzureGermanCloud"
+)
+
+func (ecfg *azureEnvironConfig) clientID() string {
+	v, _ := ecfg.UnknownAttrs()[clientIDAttr].(string)
+	return v
+}
+
+func (ecfg *azureEnvironConfig) clientSecret() string {
+	v, _ := ecfg.UnknownAttrs()[clientSecretAttr].(string)
+	return v
+}
+
+func (ecfg *azureEnvironConfig) tenantID() string {
+	v, _ := ecfg.UnknownAttrs()[tenantIDAttr].(string)
+	return v
+}
+
+func (ecfg *azureEnvironConfig) cloudEnvironment() string {
+	v, _ := ecfg.UnknownAttrs()[cloudEnvironmentAttr].(string)
+	if v == "" {
+		return AzurePublicCloud
+	}
+	return v
+}
+
+// usesServicePrincipal reports whether this config is set up for
+// service-principal authentication, i.e. has a client ID configured.
+// When false, getManagementAPI falls back to the original
+// management-certificate mode for back-compat.
+func (ecfg *azureEnvironConfig) usesServicePrincipal() bool {
+	return ecfg.clientID() != ""
+}
+
+// validateServicePrincipal requires client-secret, tenant-id and a known
+// cloud-environment once client-id is set. Without client-id nothing is
+// checked, even if client-secret or tenant-id is set.
+func (ecfg *azureEnvironConfig) validateServicePrincipal() error {
+	if !ecfg.usesServicePrincipal() {
+		return nil
+	}
+	if ecfg.clientSecret() == "" {
+		return fmt.Errorf("%s is set but %s is missing", clientIDAttr, clientSecretAttr)
+	}
+	if ecfg.tenantID() == "" {
+		return fmt.Errorf("%s is set but %s is missing", clientIDAttr, tenantIDAttr)
+	}
+	switch ecfg.cloudEnvironment() {
+	case AzurePublicCloud, AzureChinaCloud, AzureUSGovernmentCloud, AzureGermanCloud:
+	default:
+		return fmt.Errorf("invalid %s %q", cloudEnvironmentAttr, ecfg.cloudEnvironment())
+	}
+	return nil
+}
+
+// azureCloudEnvironment looks up the autorest azure.Environment matching a
+// "cloud-environment" config value.
+func azureCloudEnvironment(name string) (azure.Environment, error) {
+	return azure.EnvironmentFromName(name)
+}
+
+// servicePrincipalKey identifies one client-credentials cache entry.
+type servicePrincipalKey struct {
+	tenantID, clientID string
+}
+
+var (
+	authorizerCacheMu sync.Mutex
+	authorizerCache   = make(map[servicePrincipalKey]*cachedAuthorizer)
+)
+
+// cachedAuthorizer is a *autorest.BearerAuthorizer together with the
+// time its underlying token expires, so getServicePrincipalAuthorizer
+// knows when to refresh it rather than reusing a stale one.
+type cachedAuthorizer struct {
+	authorizer *autorest.BearerAuthorizer
+	expiresAt  time.Time
+}
+
+// getServicePrincipalAuthorizer returns a cached bearer authorizer for
+// tenantID+clientID, acquiring and caching a new one via the
+// client-credentials flow if there is none yet or the cached one has
+// expired.
+func getServicePrincipalAuthorizer(ecfg *azureEnvironConfig) (*autorest.BearerAuthorizer, error) {
+	key := servicePrincipalKey{tenantID: ecfg.tenantID(), clientID: ecfg.clientID()}
+
+	authorizerCacheMu.Lock()
+	defer authorizerCacheMu.Unlock()
+
+	if cached, ok := authorizerCache[key]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.authorizer, nil
+	}
+
+	env, err := azureCloudEnvironment(ecfg.cloudEnvironment())
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve cloud environment %q: %v", ecfg.cloudEnvironment(), err)
+	}
+	clientCfg := auth.NewClientCredentialsConfig(ecfg.clientID(), ecfg.clientSecret(), ecfg.tenantID())
+	clientCfg.Resource = env.ResourceManagerEndpoint
+	clientCfg.AADEndpoint = env.ActiveDirectoryEndpoint
+	token, err := clientCfg.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("cannot acquire service principal token: %v", err)
+	}
+	if err := token.Refresh(); err != nil {
+		return nil, fmt.Errorf("cannot refresh service principal token: %v", err)
+	}
+
+	authorizer := autorest.NewBearerAuthorizer(token)
+	authorizerCache[key] = &cachedAuthorizer{
+		authorizer: authorizer,
+		expiresAt:  token.Token().Expires(),
+	}
+	return authorizer, nil
+}
+
+// newManagementAPIWithServicePrincipal builds a gwacl ManagementAPI
+// authenticated with a bearer token instead of a management
+// certificate.
+func newManagementAPIWithServicePrincipal(ecfg *azureEnvironConfig, authorizer *autorest.BearerAuthorizer) (*gwacl.ManagementAPI, error) {
+	return gwacl.NewManagementAPIWithAuthorizer(
+		ecfg.managementSubscriptionId(), authorizer, ecfg.location(), retryPolicy)
+}
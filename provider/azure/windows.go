@@ -0,0 +1,94 @@
+// Copyright 2014 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+	"strings"
+
+	"launchpad.net/gwacl"
+)
+
+// osType identifies the guest OS family of a role, which determines how
+// it is provisioned.
+type osType int
+
+const (
+	// osUbuntu is the default: userData is wired into the role's Linux
+	// provisioning configuration set directly, the way cloud-init
+	// expects it.
+	osUbuntu osType = iota
+
+	// osWindows and osCentOS have no cloud-init equivalent, so userData
+	// is instead run through a VM extension (see vmExtensionProperties).
+	osWindows
+	osCentOS
+)
+
+// seriesOS returns the osType of series, as determined by its name.
+func seriesOS(series string) osType {
+	switch {
+	case strings.HasPrefix(series, "win"):
+		return osWindows
+	case strings.HasPrefix(series, "centos"):
+		return osCentOS
+	default:
+		return osUbuntu
+	}
+}
+
+// osDiskType returns the gwacl OS disk type string ("Linux" or
+// "Windows") for os, for use in newOSDisk.
+func osDiskType(os osType) string {
+	if os == osWindows {
+		return "Windows"
+	}
+	return "Linux"
+}
+
+// vmExtensionProperties returns the publisher/type/version/
+// commandTemplate tuple for the VM extension that runs userData on os.
+// commandTemplate has a single %s verb for the base64 userData payload.
+// ok is false for osUbuntu, which needs no extension because its Linux
+// provisioning configuration set accepts userData directly.
+func vmExtensionProperties(os osType) (publisher, extType, version, commandTemplate string, ok bool) {
+	switch os {
+	case osWindows:
+		// CustomScriptExtension decodes and invokes the payload with
+		// PowerShell; this is what createRole/newRole use to run the
+		// Juju bootstrap script on a Windows series, since Windows has
+		// no cloud-init to consume custom data directly.
+		return "Microsoft.Compute", "CustomScriptExtension", "1.*",
+			"powershell.exe -NoProfile -ExecutionPolicy unrestricted -Command " +
+				"\"[System.Text.Encoding]::UTF8.GetString([System.Convert]::FromBase64String('%s')) | Invoke-Expression\"", true
+	case osCentOS:
+		return "Microsoft.Azure.Extensions", "CustomScript", "2.*",
+			"bash -c \"echo %s | base64 --decode | bash\"", true
+	default:
+		return "", "", "", "", false
+	}
+}
+
+// vmExtension builds the gwacl VM extension reference to attach to a
+// role for os, or nil if os needs no extension. userData is the same
+// custom data payload passed to the role's provisioning configuration
+// set on Ubuntu; the extension invokes it directly as a script rather
+// than handing it to cloud-init, so it only produces a working instance
+// if userData is already in the format commandTemplate's interpreter
+// expects (a PowerShell script for osWindows). StartInstance currently
+// refuses to start an osWindows instance rather than pass it cloud-init
+// YAML here, since no such renderer exists yet - see the comment there.
+func vmExtension(os osType, userData string) *gwacl.ResourceExtensionReference {
+	publisher, extType, version, commandTemplate, ok := vmExtensionProperties(os)
+	if !ok {
+		return nil
+	}
+	return &gwacl.ResourceExtensionReference{
+		ReferenceName:    "JujuBootstrap",
+		Publisher:        publisher,
+		Name:             extType,
+		Version:          version,
+		CommandToExecute: fmt.Sprintf(commandTemplate, userData),
+	}
+}
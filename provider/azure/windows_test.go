@@ -0,0 +1,58 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package azure
+
+import (
+	"fmt"
+
+	gc "gopkg.in/check.v1"
+)
+
+type WindowsSuite struct{}
+
+var _ = gc.Suite(&WindowsSuite{})
+
+func (s *WindowsSuite) TestSeriesOS(c *gc.C) {
+	c.Assert(seriesOS("win2012r2"), gc.Equals, osWindows)
+	c.Assert(seriesOS("centos7"), gc.Equals, osCentOS)
+	c.Assert(seriesOS("trusty"), gc.Equals, osUbuntu)
+}
+
+func (s *WindowsSuite) TestOsDiskType(c *gc.C) {
+	c.Assert(osDiskType(osWindows), gc.Equals, "Windows")
+	c.Assert(osDiskType(osCentOS), gc.Equals, "Linux")
+	c.Assert(osDiskType(osUbuntu), gc.Equals, "Linux")
+}
+
+func (s *WindowsSuite) TestVmExtensionPropertiesUbuntuNeedsNoExtension(c *gc.C) {
+	_, _, _, _, ok := vmExtensionProperties(osUbuntu)
+	c.Assert(ok, gc.Equals, false)
+}
+
+func (s *WindowsSuite) TestVmExtensionPropertiesWindows(c *gc.C) {
+	publisher, extType, version, commandTemplate, ok := vmExtensionProperties(osWindows)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(publisher, gc.Equals, "Microsoft.Compute")
+	c.Assert(extType, gc.Equals, "CustomScriptExtension")
+	c.Assert(version, gc.Equals, "1.*")
+	c.Assert(fmt.Sprintf(commandTemplate, "cGF5bG9hZA=="), gc.Matches, `.*cGF5bG9hZA==.*`)
+}
+
+func (s *WindowsSuite) TestVmExtensionPropertiesCentOS(c *gc.C) {
+	publisher, extType, version, _, ok := vmExtensionProperties(osCentOS)
+	c.Assert(ok, gc.Equals, true)
+	c.Assert(publisher, gc.Equals, "Microsoft.Azure.Extensions")
+	c.Assert(extType, gc.Equals, "CustomScript")
+	c.Assert(version, gc.Equals, "2.*")
+}
+
+func (s *WindowsSuite) TestVmExtensionUbuntuNil(c *gc.C) {
+	c.Assert(vmExtension(osUbuntu, "userdata"), gc.IsNil)
+}
+
+func (s *WindowsSuite) TestVmExtensionWindowsSetsCommandToExecute(c *gc.C) {
+	ext := vmExtension(osWindows, "cGF5bG9hZA==")
+	c.Assert(ext, gc.NotNil)
+	c.Assert(ext.CommandToExecute, gc.Matches, `.*cGF5bG9hZA==.*`)
+}
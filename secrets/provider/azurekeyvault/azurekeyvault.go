@@ -0,0 +1,125 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package azurekeyvault implements secrets/provider.Provider backed by
+// an Azure Key Vault.
+package azurekeyvault
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/secrets/provider"
+)
+
+// ProviderType is the id this provider registers itself under, and the
+// value expected in a model's "secret-backend" config attribute to
+// select it.
+const ProviderType = "azure-keyvault"
+
+func init() {
+	provider.RegisterProvider(ProviderType, NewProvider)
+}
+
+type azureKeyVaultProvider struct {
+	client *azsecrets.Client
+}
+
+// NewProvider returns a Provider backed by an Azure Key Vault,
+// configured from attributes sourced from an "azure-keyvault" cloud
+// credential: "vault-url", "tenant-id", "client-id" and "client-secret".
+func NewProvider(cfg map[string]interface{}) (provider.Provider, error) {
+	vaultURL, _ := cfg["vault-url"].(string)
+	if vaultURL == "" {
+		return nil, errors.NotValidf("azure-keyvault config missing vault-url")
+	}
+	tenantID, _ := cfg["tenant-id"].(string)
+	clientID, _ := cfg["client-id"].(string)
+	clientSecret, _ := cfg["client-secret"].(string)
+
+	cred, err := azidentity.NewClientSecretCredential(tenantID, clientID, clientSecret, nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "creating azure credential")
+	}
+	client, err := azsecrets.NewClient(vaultURL, cred, nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "creating azure key vault client")
+	}
+	return &azureKeyVaultProvider{client: client}, nil
+}
+
+// StoreSecret is part of the provider.Provider interface.
+func (p *azureKeyVaultProvider) StoreSecret(ctx context.Context, uri *secrets.URI, value secrets.SecretValue) (string, error) {
+	data, err := value.EncodedValues()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	name := secretName(uri)
+	contentType := "application/json"
+	resp, err := p.client.SetSecret(ctx, name, azsecrets.SetSecretParameters{
+		Value:       stringPtr(string(raw)),
+		ContentType: &contentType,
+	}, nil)
+	if err != nil {
+		return "", errors.Annotatef(err, "writing secret %q to azure key vault", uri)
+	}
+	return string(*resp.ID), nil
+}
+
+// AccessSecret is part of the provider.Provider interface.
+func (p *azureKeyVaultProvider) AccessSecret(ctx context.Context, providerID string) (secrets.SecretValue, error) {
+	name, version := splitSecretID(providerID)
+	resp, err := p.client.GetSecret(ctx, name, version, nil)
+	if err != nil {
+		return nil, errors.Annotatef(err, "reading secret %q from azure key vault", providerID)
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(*resp.Value), &data); err != nil {
+		return nil, errors.Annotatef(err, "decoding secret %q from azure key vault", providerID)
+	}
+	return secrets.NewSecretValue(data), nil
+}
+
+// DeleteSecret is part of the provider.Provider interface.
+func (p *azureKeyVaultProvider) DeleteSecret(ctx context.Context, providerID string) error {
+	name, _ := splitSecretID(providerID)
+	if _, err := p.client.DeleteSecret(ctx, name, nil); err != nil {
+		return errors.Annotatef(err, "deleting secret %q from azure key vault", providerID)
+	}
+	return nil
+}
+
+// secretName maps a Juju secret URI onto an Azure Key Vault secret name.
+// Key Vault names may only contain letters, digits and hyphens.
+func secretName(uri *secrets.URI) string {
+	return "juju-" + uri.ID
+}
+
+// splitSecretID pulls the secret name and version out of the full
+// "<vault>/secrets/<name>/<version>" resource id Azure hands back from
+// StoreSecret.
+func splitSecretID(providerID string) (name, version string) {
+	parts := strings.Split(strings.TrimPrefix(providerID, "https://"), "/")
+	for i, p := range parts {
+		if p == "secrets" && i+1 < len(parts) {
+			name = parts[i+1]
+			if i+2 < len(parts) {
+				version = parts[i+2]
+			}
+			return name, version
+		}
+	}
+	return providerID, ""
+}
+
+func stringPtr(s string) *string { return &s }
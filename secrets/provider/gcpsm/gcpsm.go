@@ -0,0 +1,137 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package gcpsm implements secrets/provider.Provider backed by Google
+// Cloud Secret Manager.
+package gcpsm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	secretmanagerpb "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/juju/errors"
+	"google.golang.org/api/option"
+
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/secrets/provider"
+)
+
+// ProviderType is the id this provider registers itself under, and the
+// value expected in a model's "secret-backend" config attribute to
+// select it.
+const ProviderType = "gcp-secret-manager"
+
+func init() {
+	provider.RegisterProvider(ProviderType, NewProvider)
+}
+
+type gcpsmProvider struct {
+	client    *secretmanager.Client
+	projectID string
+}
+
+// NewProvider returns a Provider backed by GCP Secret Manager,
+// configured from attributes sourced from a "gcp-secret-manager" cloud
+// credential: "project-id" and "credentials-json" (a service account
+// key).
+func NewProvider(cfg map[string]interface{}) (provider.Provider, error) {
+	projectID, _ := cfg["project-id"].(string)
+	if projectID == "" {
+		return nil, errors.NotValidf("gcp-secret-manager config missing project-id")
+	}
+	creds, _ := cfg["credentials-json"].(string)
+
+	ctx := context.Background()
+	var opts []option.ClientOption
+	if creds != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(creds)))
+	}
+	client, err := secretmanager.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.Annotate(err, "creating gcp secret manager client")
+	}
+	return &gcpsmProvider{client: client, projectID: projectID}, nil
+}
+
+// StoreSecret is part of the provider.Provider interface.
+func (p *gcpsmProvider) StoreSecret(ctx context.Context, uri *secrets.URI, value secrets.SecretValue) (string, error) {
+	data, err := value.EncodedValues()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+
+	secretID := secretID(uri)
+	parent := fmt.Sprintf("projects/%s", p.projectID)
+	name := fmt.Sprintf("%s/secrets/%s", parent, secretID)
+
+	if _, err := p.client.GetSecret(ctx, &secretmanagerpb.GetSecretRequest{Name: name}); err != nil {
+		_, err = p.client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
+			Parent:   parent,
+			SecretId: secretID,
+			Secret: &secretmanagerpb.Secret{
+				Replication: &secretmanagerpb.Replication{
+					Replication: &secretmanagerpb.Replication_Automatic_{
+						Automatic: &secretmanagerpb.Replication_Automatic{},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return "", errors.Annotatef(err, "creating secret %q in gcp secret manager", uri)
+		}
+	}
+
+	version, err := p.client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
+		Parent:  name,
+		Payload: &secretmanagerpb.SecretPayload{Data: raw},
+	})
+	if err != nil {
+		return "", errors.Annotatef(err, "writing secret %q to gcp secret manager", uri)
+	}
+	return version.Name, nil
+}
+
+// AccessSecret is part of the provider.Provider interface.
+func (p *gcpsmProvider) AccessSecret(ctx context.Context, providerID string) (secrets.SecretValue, error) {
+	resp, err := p.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: providerID})
+	if err != nil {
+		return nil, errors.Annotatef(err, "reading secret %q from gcp secret manager", providerID)
+	}
+	var data map[string]string
+	if err := json.Unmarshal(resp.Payload.Data, &data); err != nil {
+		return nil, errors.Annotatef(err, "decoding secret %q from gcp secret manager", providerID)
+	}
+	return secrets.NewSecretValue(data), nil
+}
+
+// DeleteSecret is part of the provider.Provider interface.
+func (p *gcpsmProvider) DeleteSecret(ctx context.Context, providerID string) error {
+	parent := parentSecretName(providerID)
+	if err := p.client.DeleteSecret(ctx, &secretmanagerpb.DeleteSecretRequest{Name: parent}); err != nil {
+		return errors.Annotatef(err, "deleting secret %q from gcp secret manager", providerID)
+	}
+	return nil
+}
+
+// secretID maps a Juju secret URI onto a GCP Secret Manager secret id.
+// Secret ids may only contain letters, digits, underscores and hyphens.
+func secretID(uri *secrets.URI) string {
+	return "juju-" + uri.ID
+}
+
+// parentSecretName strips the "/versions/<n>" suffix a version name
+// carries to recover the parent secret's resource name.
+func parentSecretName(versionName string) string {
+	if i := strings.Index(versionName, "/versions/"); i >= 0 {
+		return versionName[:i]
+	}
+	return versionName
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package provider defines the pluggable external secret backend
+// interface and the registry used to look providers up by id, mirroring
+// the pattern used for storage and environs providers elsewhere in
+// Juju.
+package provider
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/secrets"
+)
+
+// Juju is the id of the built-in provider backed by Juju's own secret
+// store. It is never registered in the provider registry; callers treat
+// it as the default when a secret's Provider attribute is empty or set
+// to this value.
+const Juju = "juju"
+
+// Provider is implemented by each external secret backend (Vault, AWS
+// SSM, GCP Secret Manager, Azure Key Vault, ...). A Provider only deals
+// with the mechanics of storing/fetching/deleting a single secret value
+// in its own backend; Juju's own metadata about that secret (owner,
+// rotation policy, labels, history, ...) always lives in Juju's own
+// store regardless of which provider holds the payload.
+type Provider interface {
+	// StoreSecret writes value to the backend and returns an opaque,
+	// provider-specific id that AccessSecret and DeleteSecret can later
+	// use to locate it again. Juju persists this id alongside the
+	// secret's metadata as ProviderID.
+	StoreSecret(ctx context.Context, uri *secrets.URI, value secrets.SecretValue) (providerID string, err error)
+
+	// AccessSecret fetches the value previously stored under
+	// providerID.
+	AccessSecret(ctx context.Context, providerID string) (secrets.SecretValue, error)
+
+	// DeleteSecret removes the value stored under providerID.
+	DeleteSecret(ctx context.Context, providerID string) error
+}
+
+// NewProviderFunc returns a new instance of a Provider, configured from
+// attributes typically sourced from a cloud credential of the matching
+// provider type.
+type NewProviderFunc func(config map[string]interface{}) (Provider, error)
+
+var (
+	providersMu sync.Mutex
+	providers   = make(map[string]NewProviderFunc)
+)
+
+// RegisterProvider registers a factory for the external secret backend
+// identified by id. It panics if id is already registered; providers are
+// expected to register themselves from an init function, so a duplicate
+// registration is a programming error rather than something to recover
+// from at runtime.
+func RegisterProvider(id string, factory NewProviderFunc) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, dup := providers[id]; dup {
+		panic("provider: RegisterProvider called twice for id " + id)
+	}
+	providers[id] = factory
+}
+
+// NewProvider looks up the factory registered for id and uses it to
+// build a Provider from the supplied config.
+func NewProvider(id string, config map[string]interface{}) (Provider, error) {
+	providersMu.Lock()
+	factory, ok := providers[id]
+	providersMu.Unlock()
+	if !ok {
+		return nil, errors.NotFoundf("secret provider %q", id)
+	}
+	p, err := factory(config)
+	if err != nil {
+		return nil, errors.Annotatef(err, "creating %q secret provider", id)
+	}
+	return p, nil
+}
+
+// SupportedProviders returns the ids of all registered external
+// providers, sorted for deterministic output.
+func SupportedProviders() []string {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	ids := make([]string, 0, len(providers))
+	for id := range providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
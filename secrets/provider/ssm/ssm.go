@@ -0,0 +1,112 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package ssm implements secrets/provider.Provider backed by AWS
+// Systems Manager Parameter Store, using SecureString parameters.
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/secrets/provider"
+)
+
+// ProviderType is the id this provider registers itself under, and the
+// value expected in a model's "secret-backend" config attribute to
+// select it.
+const ProviderType = "aws-ssm"
+
+func init() {
+	provider.RegisterProvider(ProviderType, NewProvider)
+}
+
+type ssmProvider struct {
+	client *ssm.Client
+}
+
+// NewProvider returns a Provider backed by AWS SSM Parameter Store,
+// configured from attributes sourced from an "aws-ssm" cloud credential:
+// "access-key", "secret-key" and "region".
+func NewProvider(cfg map[string]interface{}) (provider.Provider, error) {
+	region, _ := cfg["region"].(string)
+	if region == "" {
+		return nil, errors.NotValidf("aws-ssm config missing region")
+	}
+	accessKey, _ := cfg["access-key"].(string)
+	secretKey, _ := cfg["secret-key"].(string)
+
+	awsCfg := aws.Config{
+		Region:      region,
+		Credentials: aws.NewCredentialsCache(credentialsProvider{accessKey, secretKey}),
+	}
+	return &ssmProvider{client: ssm.NewFromConfig(awsCfg)}, nil
+}
+
+type credentialsProvider struct {
+	accessKey, secretKey string
+}
+
+// Retrieve is part of the aws.CredentialsProvider interface.
+func (c credentialsProvider) Retrieve(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: c.accessKey, SecretAccessKey: c.secretKey}, nil
+}
+
+// StoreSecret is part of the provider.Provider interface.
+func (p *ssmProvider) StoreSecret(ctx context.Context, uri *secrets.URI, value secrets.SecretValue) (string, error) {
+	data, err := value.EncodedValues()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	name := parameterName(uri)
+	_, err = p.client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(string(raw)),
+		Type:      types.ParameterTypeSecureString,
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errors.Annotatef(err, "writing secret %q to ssm", uri)
+	}
+	return name, nil
+}
+
+// AccessSecret is part of the provider.Provider interface.
+func (p *ssmProvider) AccessSecret(ctx context.Context, providerID string) (secrets.SecretValue, error) {
+	out, err := p.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(providerID),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "reading secret %q from ssm", providerID)
+	}
+	var data map[string]string
+	if err := json.Unmarshal([]byte(aws.ToString(out.Parameter.Value)), &data); err != nil {
+		return nil, errors.Annotatef(err, "decoding secret %q from ssm", providerID)
+	}
+	return secrets.NewSecretValue(data), nil
+}
+
+// DeleteSecret is part of the provider.Provider interface.
+func (p *ssmProvider) DeleteSecret(ctx context.Context, providerID string) error {
+	_, err := p.client.DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: aws.String(providerID)})
+	if err != nil {
+		return errors.Annotatef(err, "deleting secret %q from ssm", providerID)
+	}
+	return nil
+}
+
+// parameterName maps a Juju secret URI onto an SSM parameter name.
+func parameterName(uri *secrets.URI) string {
+	return "/juju/secrets/" + uri.ID
+}
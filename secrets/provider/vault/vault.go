@@ -0,0 +1,99 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package vault implements secrets/provider.Provider backed by a
+// HashiCorp Vault KV version 2 mount.
+package vault
+
+import (
+	"context"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/core/secrets"
+	"github.com/juju/juju/secrets/provider"
+)
+
+// ProviderType is the id this provider registers itself under, and the
+// value expected in a model's "secret-backend" config attribute to
+// select it.
+const ProviderType = "vault"
+
+func init() {
+	provider.RegisterProvider(ProviderType, NewProvider)
+}
+
+type vaultProvider struct {
+	client *vaultapi.Client
+	mount  string
+}
+
+// NewProvider returns a Provider backed by a Vault KV v2 mount,
+// configured from attributes sourced from a "vault" cloud credential:
+// "endpoint", "token" and an optional "mount" (defaults to "secret").
+func NewProvider(cfg map[string]interface{}) (provider.Provider, error) {
+	endpoint, _ := cfg["endpoint"].(string)
+	if endpoint == "" {
+		return nil, errors.NotValidf("vault config missing endpoint")
+	}
+	token, _ := cfg["token"].(string)
+	mount, _ := cfg["mount"].(string)
+	if mount == "" {
+		mount = "secret"
+	}
+
+	vc := vaultapi.DefaultConfig()
+	vc.Address = endpoint
+	client, err := vaultapi.NewClient(vc)
+	if err != nil {
+		return nil, errors.Annotate(err, "creating vault client")
+	}
+	client.SetToken(token)
+
+	return &vaultProvider{client: client, mount: mount}, nil
+}
+
+// StoreSecret is part of the provider.Provider interface.
+func (p *vaultProvider) StoreSecret(ctx context.Context, uri *secrets.URI, value secrets.SecretValue) (string, error) {
+	data, err := value.EncodedValues()
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	path := secretPath(uri)
+	kvData := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		kvData[k] = v
+	}
+	if _, err := p.client.KVv2(p.mount).Put(ctx, path, kvData); err != nil {
+		return "", errors.Annotatef(err, "writing secret %q to vault", uri)
+	}
+	return path, nil
+}
+
+// AccessSecret is part of the provider.Provider interface.
+func (p *vaultProvider) AccessSecret(ctx context.Context, providerID string) (secrets.SecretValue, error) {
+	s, err := p.client.KVv2(p.mount).Get(ctx, providerID)
+	if err != nil {
+		return nil, errors.Annotatef(err, "reading secret %q from vault", providerID)
+	}
+	data := make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		str, _ := v.(string)
+		data[k] = str
+	}
+	return secrets.NewSecretValue(data), nil
+}
+
+// DeleteSecret is part of the provider.Provider interface.
+func (p *vaultProvider) DeleteSecret(ctx context.Context, providerID string) error {
+	if err := p.client.KVv2(p.mount).DeleteMetadata(ctx, providerID); err != nil {
+		return errors.Annotatef(err, "deleting secret %q from vault", providerID)
+	}
+	return nil
+}
+
+// secretPath maps a Juju secret URI onto a path under the Vault mount.
+func secretPath(uri *secrets.URI) string {
+	return "juju/" + uri.ID
+}
@@ -0,0 +1,69 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	coretesting "github.com/juju/juju/testing"
+)
+
+// ActionsRegistrySuite exercises the registry in actions_registry.go
+// directly, as distinct from MachineSuite's
+// TestValidateMachineActionCustomRegistration, which only covers it
+// indirectly via a live *Machine. It does not exercise the resulting
+// state.Action being queued: Machine.AddAction's fixed name/schema
+// switch - the thing that would create that Action - lives outside this
+// checkout, as actions_registry.go's file comment notes, so there is no
+// AddAction call here for a custom-registered name to observe.
+type ActionsRegistrySuite struct {
+	coretesting.BaseSuite
+}
+
+var _ = gc.Suite(&ActionsRegistrySuite{})
+
+func (s *ActionsRegistrySuite) TestRegisterAndValidateCustomAction(c *gc.C) {
+	err := state.RegisterMachineAction("disk-check", `{
+		"$schema": "http://json-schema.org/draft-04/schema#",
+		"title": "disk-check",
+		"type": "object",
+		"properties": {
+			"path": {"type": "string"}
+		},
+		"required": ["path"]
+	}`)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(state.RegisteredMachineActions(), jc.Contains, "disk-check")
+
+	err = state.ValidateMachineAction("disk-check", map[string]interface{}{"path": "/"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = state.ValidateMachineAction("disk-check", map[string]interface{}{})
+	c.Assert(err, gc.ErrorMatches, `validation failed: .*"path" property is missing and required.*`)
+}
+
+func (s *ActionsRegistrySuite) TestValidateUnregisteredAction(c *gc.C) {
+	err := state.ValidateMachineAction("not-a-real-action", nil)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *ActionsRegistrySuite) TestRegisterDuplicateActionPanics(c *gc.C) {
+	err := state.RegisterMachineAction("duplicate-check", `{
+		"$schema": "http://json-schema.org/draft-04/schema#",
+		"title": "duplicate-check",
+		"type": "object"
+	}`)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(func() {
+		_ = state.RegisterMachineAction("duplicate-check", `{
+			"$schema": "http://json-schema.org/draft-04/schema#",
+			"title": "duplicate-check",
+			"type": "object"
+		}`)
+	}, gc.PanicMatches, `state: RegisterMachineAction called twice for name duplicate-check`)
+}
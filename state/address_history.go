@@ -0,0 +1,231 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+const machineAddressHistoryC = "machineAddressHistory"
+
+const (
+	// defaultMaxAddressHistoryEvents bounds how many address-change
+	// events are kept per machine when the model config does not
+	// override "address-history-max-events".
+	defaultMaxAddressHistoryEvents = 100
+
+	// defaultMaxAddressHistoryAge bounds how old an address-change
+	// event may be before it is pruned, when the model config does not
+	// override "address-history-max-age".
+	defaultMaxAddressHistoryAge = 30 * 24 * time.Hour
+)
+
+// AddressSource identifies who reported an address change recorded in a
+// machine's address history.
+type AddressSource string
+
+const (
+	// AddressSourceProvider marks an event written by SetProviderAddresses.
+	AddressSourceProvider AddressSource = "provider"
+
+	// AddressSourceMachine marks an event written by SetMachineAddresses.
+	AddressSourceMachine AddressSource = "machine"
+)
+
+// AddressEvent is one recorded transition of a machine's provider or
+// machine addresses.
+type AddressEvent struct {
+	Timestamp time.Time
+	Source    AddressSource
+	Before    []network.Address
+	After     []network.Address
+	TxnRevno  int64
+}
+
+// addressHistoryDoc is the persistent form of an AddressEvent.
+type addressHistoryDoc struct {
+	DocID     string            `bson:"_id"`
+	MachineId string            `bson:"machineid"`
+	Timestamp time.Time         `bson:"timestamp"`
+	Source    AddressSource     `bson:"source"`
+	Before    []network.Address `bson:"before"`
+	After     []network.Address `bson:"after"`
+	TxnRevno  int64             `bson:"txn-revno"`
+}
+
+// addressHistoryConfig is the effective pruning configuration for address
+// history, resolved from model config with built-in defaults.
+type addressHistoryConfig struct {
+	maxEvents int
+	maxAge    time.Duration
+}
+
+// addressHistoryConfigFor resolves the pruning configuration for st's
+// model, falling back to defaultMaxAddressHistoryEvents and
+// defaultMaxAddressHistoryAge for any attribute that is unset.
+func addressHistoryConfigFor(st *State) (addressHistoryConfig, error) {
+	cfg := addressHistoryConfig{
+		maxEvents: defaultMaxAddressHistoryEvents,
+		maxAge:    defaultMaxAddressHistoryAge,
+	}
+	modelCfg, err := st.ModelConfig()
+	if err != nil {
+		return addressHistoryConfig{}, errors.Trace(err)
+	}
+	attrs := modelCfg.AllAttrs()
+	if max, ok := attrs["address-history-max-events"].(int); ok && max > 0 {
+		cfg.maxEvents = max
+	}
+	if maxAge, ok := attrs["address-history-max-age"].(string); ok && maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return addressHistoryConfig{}, errors.Annotatef(err, "parsing address-history-max-age")
+		}
+		cfg.maxAge = d
+	}
+	return cfg, nil
+}
+
+// addressHistoryOps returns the txn.Op needed to append an address-change
+// event to the machine's history, for inclusion in the same transaction
+// that updates the machine's addresses field. revno is the machine doc's
+// txn-revno as observed before this transaction's update, not the value
+// the doc carries once the enclosing transaction commits.
+//
+// This is only the history-collection insert. It deliberately does not
+// also return a txn.Op against the machine doc: the caller already has
+// one of those (to update the addresses field itself), and mgo/txn
+// rejects two ops referencing the same (collection, id) pair in one
+// transaction. The caller is responsible for folding the
+// "addresshistorycount" increment into its own machinesC op.
+func (m *Machine) addressHistoryOps(source AddressSource, before, after []network.Address, revno int64) (txn.Op, error) {
+	now := time.Now()
+	doc := &addressHistoryDoc{
+		DocID:     m.st.docID(m.globalKey() + "#addrhist#" + now.Format(time.RFC3339Nano)),
+		MachineId: m.Id(),
+		Timestamp: now,
+		Source:    source,
+		Before:    before,
+		After:     after,
+		TxnRevno:  revno,
+	}
+	return txn.Op{
+		C:      machineAddressHistoryC,
+		Id:     doc.DocID,
+		Assert: txn.DocMissing,
+		Insert: doc,
+	}, nil
+}
+
+// machineTxnRevno reads the current txn-revno of the machine doc with the
+// given id, for recording alongside an address-history event so it can be
+// correlated with the mgo/txn oplog.
+func machineTxnRevno(st *State, docID string) (int64, error) {
+	coll, closer := st.db().GetCollection(machinesC)
+	defer closer()
+
+	var doc struct {
+		TxnRevno int64 `bson:"txn-revno"`
+	}
+	if err := coll.FindId(docID).One(&doc); err != nil {
+		return 0, errors.Trace(err)
+	}
+	return doc.TxnRevno, nil
+}
+
+// AddressHistory returns this machine's recorded address-change events
+// since the given time, oldest first. Events older than the model's
+// configured retention are not guaranteed to still be present; see
+// PruneAddressHistory.
+func (m *Machine) AddressHistory(since time.Time) ([]AddressEvent, error) {
+	coll, closer := m.st.db().GetCollection(machineAddressHistoryC)
+	defer closer()
+
+	var docs []addressHistoryDoc
+	query := bson.D{{"machineid", m.Id()}}
+	if !since.IsZero() {
+		query = append(query, bson.DocElem{Name: "timestamp", Value: bson.D{{"$gte", since}}})
+	}
+	if err := coll.Find(query).Sort("timestamp").All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	events := make([]AddressEvent, len(docs))
+	for i, doc := range docs {
+		events[i] = AddressEvent{
+			Timestamp: doc.Timestamp,
+			Source:    doc.Source,
+			Before:    doc.Before,
+			After:     doc.After,
+			TxnRevno:  doc.TxnRevno,
+		}
+	}
+	return events, nil
+}
+
+// PruneAddressHistory removes this machine's address-history events that
+// fall outside the model's configured retention (oldest-first beyond
+// maxEvents, or older than maxAge), whichever is more restrictive.
+func (m *Machine) PruneAddressHistory() error {
+	cfg, err := addressHistoryConfigFor(m.st)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	coll, closer := m.st.db().GetCollection(machineAddressHistoryC)
+	defer closer()
+
+	var ids []string
+	var doc struct {
+		DocID string `bson:"_id"`
+	}
+	iter := coll.Find(bson.D{{"machineid", m.Id()}}).Sort("-timestamp").Select(bson.D{{"_id", 1}}).Iter()
+	count := 0
+	cutoff := time.Now().Add(-cfg.maxAge)
+	for iter.Next(&doc) {
+		count++
+		if count > cfg.maxEvents {
+			ids = append(ids, doc.DocID)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return errors.Trace(err)
+	}
+
+	var expired []addressHistoryDoc
+	if err := coll.Find(bson.D{
+		{"machineid", m.Id()},
+		{"timestamp", bson.D{{"$lt", cutoff}}},
+	}).Select(bson.D{{"_id", 1}}).All(&expired); err != nil {
+		return errors.Trace(err)
+	}
+	for _, d := range expired {
+		ids = append(ids, d.DocID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+	ops := make([]txn.Op, len(ids))
+	for i, id := range ids {
+		ops[i] = txn.Op{C: machineAddressHistoryC, Id: id, Remove: true}
+	}
+	return m.st.db().RunTransaction(ops)
+}
+
+// WatchAddressHistory returns a NotifyWatcher that fires whenever a new
+// event is appended to this machine's address history, so that a facade
+// backing `juju status --history` can push updates without polling. It
+// watches the machine doc's addresshistorycount field rather than the
+// open-ended history collection directly, since NotifyWatcher watches a
+// single document.
+func (m *Machine) WatchAddressHistory() NotifyWatcher {
+	return newNotifyCollWatcher(m.st, machinesC, m.doc.DocID)
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// addressKey is the stable tuple addressReconciler hash-joins desired
+// and existing address sets on. Two addresses that are equal under this
+// key are considered the same address even if they arrived in a
+// different order.
+type addressKey struct {
+	value    string
+	addrType network.AddressType
+	scope    network.Scope
+	spaceID  string
+}
+
+func keyOf(addr network.Address) addressKey {
+	return addressKey{
+		value:    addr.Value,
+		addrType: addr.Type,
+		scope:    addressScope(addr),
+		spaceID:  addr.SpaceID,
+	}
+}
+
+// addressDelta is the result of reconciling a desired address list
+// against what is currently persisted: the addresses to add and the
+// addresses to remove. An empty delta means the sets are identical
+// (possibly after reordering), so the caller can skip writing anything
+// and avoid bumping the machine doc's txn-revno.
+type addressDelta struct {
+	toAdd    []network.Address
+	toRemove []network.Address
+}
+
+// IsEmpty reports whether applying this delta would be a no-op.
+func (d addressDelta) IsEmpty() bool {
+	return len(d.toAdd) == 0 && len(d.toRemove) == 0
+}
+
+// reconcileAddresses hash-joins desired against existing, keyed by
+// (value, type, scope, spaceID), and returns only the entries that
+// differ. This replaces a full-replacement diff so that re-setting an
+// identical address list - the common case, since providers re-report
+// the same addresses on every poll - produces no delta at all.
+func reconcileAddresses(existing, desired []network.Address) addressDelta {
+	existingByKey := make(map[addressKey]network.Address, len(existing))
+	for _, addr := range existing {
+		existingByKey[keyOf(addr)] = addr
+	}
+	desiredByKey := make(map[addressKey]network.Address, len(desired))
+	for _, addr := range desired {
+		desiredByKey[keyOf(addr)] = addr
+	}
+
+	var delta addressDelta
+	for key, addr := range desiredByKey {
+		if _, ok := existingByKey[key]; !ok {
+			delta.toAdd = append(delta.toAdd, addr)
+		}
+	}
+	for key, addr := range existingByKey {
+		if _, ok := desiredByKey[key]; !ok {
+			delta.toRemove = append(delta.toRemove, addr)
+		}
+	}
+	return delta
+}
+
+// addressReconcileOps builds the targeted $push/$pull ops needed to
+// bring field (e.g. "addresses" or "machineaddresses") on the machine
+// doc from its current value to desired, using reconcileAddresses to
+// compute the minimal delta, plus an addressHistoryOps entry recording
+// the transition under source. It returns no ops - not even an Assert -
+// if the sets are equal, so that applying an unchanged address list
+// never touches the machine doc's txn-revno, never wakes its watchers,
+// and never writes a spurious history event.
+func (m *Machine) addressReconcileOps(field string, source AddressSource, existing, desired []network.Address) ([]txn.Op, error) {
+	delta := reconcileAddresses(existing, desired)
+	if delta.IsEmpty() {
+		return nil, nil
+	}
+
+	set := bson.D{}
+	if len(delta.toRemove) > 0 {
+		set = append(set, bson.DocElem{Name: "$pullAll", Value: bson.D{{field, delta.toRemove}}})
+	}
+	if len(delta.toAdd) > 0 {
+		set = append(set, bson.DocElem{Name: "$push", Value: bson.D{{field, bson.D{{"$each", delta.toAdd}}}}})
+	}
+	// Folded into the same machinesC op rather than a separate one, since
+	// addressHistoryOps's insert is the only other op in this
+	// transaction and mgo/txn rejects two ops against the same
+	// (collection, id) pair.
+	set = append(set, bson.DocElem{Name: "$inc", Value: bson.D{{"addresshistorycount", 1}}})
+
+	revno, err := machineTxnRevno(m.st, m.doc.DocID)
+	if err != nil {
+		return nil, err
+	}
+	historyOp, err := m.addressHistoryOps(source, existing, desired, revno)
+	if err != nil {
+		return nil, err
+	}
+
+	return []txn.Op{
+		{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: txn.DocExists,
+			Update: set,
+		},
+		historyOp,
+	}, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/network"
+)
+
+// AddressesInScope filters this machine's merged provider+machine
+// addresses down to those in the requested scope.
+func (m *Machine) AddressesInScope(scope network.Scope) ([]network.Address, error) {
+	addresses, err := m.Addresses()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	matches := make([]network.Address, 0, len(addresses))
+	for _, addr := range addresses {
+		if addressScope(addr) == scope {
+			matches = append(matches, addr)
+		}
+	}
+	return matches, nil
+}
+
+// SelectAddressByScope picks the best address for a consumer that wants
+// the given scope: an exact scope match is preferred, falling back
+// through the other scopes in the order scopeFallbackOrder gives. For
+// example a request for ScopePublic falls back to ScopeCloudLocal, then
+// ScopeFanLocal, then ScopeMachineLocal if no public address is set,
+// since a same-cloud caller can usually still reach a cloud-local
+// address.
+func (m *Machine) SelectAddressByScope(scope network.Scope) (network.Address, error) {
+	addresses, err := m.Addresses()
+	if err != nil {
+		return network.Address{}, errors.Trace(err)
+	}
+	addr, ok := selectByScope(addresses, scope)
+	if !ok {
+		return network.Address{}, errors.NotFoundf("address in scope %q for machine %s", scope, m.Id())
+	}
+	return addr, nil
+}
+
+// selectByScope picks the best address out of addresses for scope,
+// falling back through scopeFallbackOrder. It reports false if addresses
+// is empty or none of them match any scope in the fallback order.
+func selectByScope(addresses []network.Address, scope network.Scope) (network.Address, bool) {
+	for _, candidate := range scopeFallbackOrder(scope) {
+		for _, addr := range addresses {
+			if addressScope(addr) == candidate {
+				return addr, true
+			}
+		}
+	}
+	return network.Address{}, false
+}
+
+// scopeFallbackOrder returns the scopes to try, in order, when a
+// consumer asks for the given scope but no address with exactly that
+// scope is set.
+func scopeFallbackOrder(scope network.Scope) []network.Scope {
+	switch scope {
+	case network.ScopePublic:
+		return []network.Scope{network.ScopePublic, network.ScopeCloudLocal, network.ScopeFanLocal, network.ScopeMachineLocal}
+	case network.ScopeCloudLocal:
+		return []network.Scope{network.ScopeCloudLocal, network.ScopeFanLocal, network.ScopeMachineLocal, network.ScopePublic}
+	default:
+		return []network.Scope{scope, network.ScopeCloudLocal, network.ScopePublic}
+	}
+}
+
+// PublicAddress returns the address to use for consumers outside the
+// cloud. It is a thin wrapper around SelectAddressByScope, and is the
+// only definition of this method on *Machine: a second PublicAddress
+// elsewhere in the package would be a method-redeclared compile error.
+func (m *Machine) PublicAddress() (network.Address, error) {
+	return m.SelectAddressByScope(network.ScopePublic)
+}
+
+// PrivateAddress returns the address to use for consumers inside the
+// cloud. It is a thin wrapper around SelectAddressByScope, and is the
+// only definition of this method on *Machine; see PublicAddress.
+func (m *Machine) PrivateAddress() (network.Address, error) {
+	return m.SelectAddressByScope(network.ScopeCloudLocal)
+}
+
+// addressScope returns addr's explicit scope if set, otherwise infers it
+// from the address's CIDR/IP classification.
+func addressScope(addr network.Address) network.Scope {
+	if addr.Scope != "" {
+		return addr.Scope
+	}
+	return network.ScopeFromAddress(addr.Value)
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/network"
+)
+
+// AddressForSpace returns the best address for this machine that is bound
+// to spaceID, e.g. for a unit relation whose endpoint is bound to that
+// space. The merged provider+machine address list is first filtered down
+// to addresses whose SpaceID matches, then the cloud-local preference
+// order PrivateAddress uses (cloud-local, fan-local, machine-local, then
+// public; see scopeFallbackOrder) is applied within that subset. If no address resolves to spaceID - typically because a
+// provider address matches no known subnet and so was never assigned a
+// SpaceID - this falls through to the same cloud-local scope match used
+// by PrivateAddress today.
+func (m *Machine) AddressForSpace(spaceID string) (network.Address, error) {
+	addresses, err := m.Addresses()
+	if err != nil {
+		return network.Address{}, errors.Trace(err)
+	}
+
+	inSpace := make([]network.Address, 0, len(addresses))
+	for _, addr := range addresses {
+		if addr.SpaceID == spaceID {
+			inSpace = append(inSpace, addr)
+		}
+	}
+	if len(inSpace) == 0 {
+		addr, ok := selectByScope(addresses, network.ScopeCloudLocal)
+		if !ok {
+			return network.Address{}, errors.NotFoundf("address for space %q on machine %s", spaceID, m.Id())
+		}
+		return addr, nil
+	}
+
+	addr, ok := selectByScope(inSpace, network.ScopeCloudLocal)
+	if !ok {
+		// Every address in the space has a scope outside the fallback
+		// order (e.g. all link-local); take the first one rather than
+		// reporting not-found for a space we know has addresses.
+		return inSpace[0], nil
+	}
+	return addr, nil
+}
+
+// migrateAddressSpaceIDsOps returns the txn.Ops that backfill SpaceID on
+// addr against st's known subnets: if addr's value falls inside exactly
+// one subnet's CIDR, that subnet's SpaceID is attached. Addresses that
+// match no subnet, or match more than one (which should not normally
+// happen, but subnets are operator-managed data), are left unchanged
+// rather than guessed at.
+func migrateAddressSpaceIDsOps(st *State, field string, m *Machine, addresses []network.Address) ([]txn.Op, []network.Address, error) {
+	subnets, err := st.AllSubnets()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	migrated := make([]network.Address, len(addresses))
+	changed := false
+	for i, addr := range addresses {
+		migrated[i] = addr
+		if addr.SpaceID != "" {
+			continue
+		}
+		var match *Subnet
+		for _, subnet := range subnets {
+			if subnet.Contains(addr.Value) {
+				if match != nil {
+					match = nil
+					break
+				}
+				match = subnet
+			}
+		}
+		if match != nil {
+			migrated[i].SpaceID = match.SpaceID()
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, addresses, nil
+	}
+
+	return []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.DocID,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{{field, migrated}}}},
+	}}, migrated, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/status"
+)
+
+// This file covers the state-side half of treating Kubernetes as a
+// container type peer to LXC/KVM (instance.KUBERNETES): the pod spec a
+// container machine of that type is provisioned with, and translating
+// the cluster's observed pod phase into the machine's instance status.
+// The instance.ContainerType constant itself, the provisioner worker that
+// drives a kubeconfig-defined cluster from this state, and the
+// `add-machine` CLI plumbing all live in packages not present in this
+// checkout and are not touched here.
+
+const kubernetesPodSpecC = "kubernetesPodSpec"
+
+// PodPhase mirrors a Kubernetes pod's phase, as reported by the
+// provisioner's watch of the container's pod.
+type PodPhase string
+
+const (
+	PodPending   PodPhase = "Pending"
+	PodRunning   PodPhase = "Running"
+	PodSucceeded PodPhase = "Succeeded"
+	PodFailed    PodPhase = "Failed"
+	PodUnknown   PodPhase = "Unknown"
+)
+
+// PodSpec describes how a Kubernetes-container-type machine is
+// provisioned as a pod: the image to run and the resources to request,
+// mirroring the subset of a Kubernetes PodSpec that Juju needs to
+// reconcile create/destroy against a kubeconfig-defined cluster.
+type PodSpec struct {
+	Image         string
+	Command       []string
+	Env           map[string]string
+	CPURequest    string
+	MemoryRequest string
+	Namespace     string
+}
+
+// kubernetesPodSpecDoc is the persistent form of a PodSpec.
+type kubernetesPodSpecDoc struct {
+	DocID     string            `bson:"_id"`
+	MachineId string            `bson:"machineid"`
+	Image     string            `bson:"image"`
+	Command   []string          `bson:"command"`
+	Env       map[string]string `bson:"env"`
+	CPU       string            `bson:"cpu"`
+	Memory    string            `bson:"memory"`
+	Namespace string            `bson:"namespace"`
+}
+
+// SetPodSpec records the pod spec a Kubernetes-container-type machine
+// should be provisioned with. It is the provisioner's source of truth
+// for the create-Pod call against the target cluster.
+func (m *Machine) SetPodSpec(spec PodSpec) error {
+	docID := m.globalKey() + "#podspec"
+	doc := &kubernetesPodSpecDoc{
+		DocID:     docID,
+		MachineId: m.Id(),
+		Image:     spec.Image,
+		Command:   spec.Command,
+		Env:       spec.Env,
+		CPU:       spec.CPURequest,
+		Memory:    spec.MemoryRequest,
+		Namespace: spec.Namespace,
+	}
+
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		_, err := m.PodSpec()
+		if errors.IsNotFound(err) {
+			return []txn.Op{{
+				C:      kubernetesPodSpecC,
+				Id:     docID,
+				Assert: txn.DocMissing,
+				Insert: doc,
+			}}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []txn.Op{{
+			C:      kubernetesPodSpecC,
+			Id:     docID,
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{
+				{"image", doc.Image},
+				{"command", doc.Command},
+				{"env", doc.Env},
+				{"cpu", doc.CPU},
+				{"memory", doc.Memory},
+				{"namespace", doc.Namespace},
+			}}},
+		}}, nil
+	}
+	if err := m.st.db().Run(buildTxn); err != nil {
+		return errors.Annotatef(err, "cannot set pod spec for machine %s", m.Id())
+	}
+	return nil
+}
+
+// PodSpec returns the pod spec previously set by SetPodSpec.
+func (m *Machine) PodSpec() (*PodSpec, error) {
+	coll, closer := m.st.db().GetCollection(kubernetesPodSpecC)
+	defer closer()
+
+	var doc kubernetesPodSpecDoc
+	if err := coll.FindId(m.globalKey() + "#podspec").One(&doc); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, errors.NotFoundf("pod spec for machine %s", m.Id())
+		}
+		return nil, errors.Trace(err)
+	}
+	return &PodSpec{
+		Image:         doc.Image,
+		Command:       doc.Command,
+		Env:           doc.Env,
+		CPURequest:    doc.CPU,
+		MemoryRequest: doc.Memory,
+		Namespace:     doc.Namespace,
+	}, nil
+}
+
+// ReconcilePodStatus translates the cluster's observed pod phase into
+// this machine's instance status, for the provisioner to call after each
+// watch event on the container's pod.
+func (m *Machine) ReconcilePodStatus(phase PodPhase) error {
+	var instanceStatus status.Status
+	var message string
+	switch phase {
+	case PodPending, PodUnknown:
+		instanceStatus, message = status.StatusPending, string(phase)
+	case PodRunning, PodSucceeded:
+		instanceStatus, message = status.StatusRunning, string(phase)
+	case PodFailed:
+		instanceStatus, message = status.StatusError, "pod failed"
+	default:
+		return errors.Errorf("unknown pod phase %q", phase)
+	}
+	return m.SetInstanceStatus(instanceStatus, message, nil)
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/core/status"
+	"github.com/juju/juju/instance"
+)
+
+// ContainerLimits bounds how many containers of a given type a machine
+// will host, and the resource envelope the provisioner must keep each of
+// them within before placing a new one.
+type ContainerLimits struct {
+	// MaxContainers is the maximum number of containers of this type the
+	// machine will host. Zero means unlimited.
+	MaxContainers int
+
+	// CPUCores is the maximum CPU cores a single container of this type
+	// may request.
+	CPUCores int
+
+	// MemMB is the maximum memory, in megabytes, a single container of
+	// this type may request.
+	MemMB int
+
+	// RootDiskMB is the maximum root disk size, in megabytes, a single
+	// container of this type may request.
+	RootDiskMB int
+}
+
+// containerLimitsDoc is the bson form of a ContainerLimits, keyed by
+// container type within the supportedContainerLimits field.
+type containerLimitsDoc struct {
+	MaxContainers int `bson:"maxcontainers"`
+	CPUCores      int `bson:"cpucores"`
+	MemMB         int `bson:"memmb"`
+	RootDiskMB    int `bson:"rootdiskmb"`
+}
+
+// SetSupportedContainersWithLimits is a sibling of SetSupportedContainers
+// that additionally records, per supported container type, the limits
+// the provisioner must enforce before placing a new container of that
+// type inside the machine. Calling it supersedes any limits set by a
+// previous call; a type with no entry in limits is supported without a
+// cap, matching today's SetSupportedContainers behaviour.
+//
+// This only sets the supportedContainerLimits field; it does not itself
+// change which container types are supported - callers should still call
+// SetSupportedContainers with the same set of types in the same
+// operation, once that function is extended (it lives outside this
+// checkout) to run both updates in a single transaction.
+func (m *Machine) SetSupportedContainersWithLimits(limits map[instance.ContainerType]ContainerLimits) error {
+	docs := make(map[string]containerLimitsDoc, len(limits))
+	for containerType, limit := range limits {
+		docs[string(containerType)] = containerLimitsDoc{
+			MaxContainers: limit.MaxContainers,
+			CPUCores:      limit.CPUCores,
+			MemMB:         limit.MemMB,
+			RootDiskMB:    limit.RootDiskMB,
+		}
+	}
+
+	ops := []txn.Op{{
+		C:      machinesC,
+		Id:     m.doc.DocID,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{{"supportedcontainerlimits", docs}}}},
+	}}
+	if err := m.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot set supported container limits for machine %s", m.Id())
+	}
+	return nil
+}
+
+// SupportedContainerLimits returns the container limits most recently
+// set by SetSupportedContainersWithLimits. A container type with no
+// entry is supported without a cap.
+func (m *Machine) SupportedContainerLimits() (map[instance.ContainerType]ContainerLimits, error) {
+	coll, closer := m.st.db().GetCollection(machinesC)
+	defer closer()
+
+	var doc struct {
+		SupportedContainerLimits map[string]containerLimitsDoc `bson:"supportedcontainerlimits"`
+	}
+	if err := coll.FindId(m.doc.DocID).One(&doc); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	limits := make(map[instance.ContainerType]ContainerLimits, len(doc.SupportedContainerLimits))
+	for containerType, limit := range doc.SupportedContainerLimits {
+		limits[instance.ContainerType(containerType)] = ContainerLimits{
+			MaxContainers: limit.MaxContainers,
+			CPUCores:      limit.CPUCores,
+			MemMB:         limit.MemMB,
+			RootDiskMB:    limit.RootDiskMB,
+		}
+	}
+	return limits, nil
+}
+
+// CheckContainerLimit reports whether placing one more container of
+// containerType, given existingCount already placed, would exceed the
+// limit recorded for that type in limits. A type absent from limits, or
+// with MaxContainers zero, is treated as unbounded.
+func CheckContainerLimit(limits map[instance.ContainerType]ContainerLimits, containerType instance.ContainerType, existingCount int) error {
+	limit, ok := limits[containerType]
+	if !ok || limit.MaxContainers == 0 {
+		return nil
+	}
+	if existingCount >= limit.MaxContainers {
+		return errors.Errorf("container limit exceeded")
+	}
+	return nil
+}
+
+// SetContainerLimitExceeded marks this container machine as having
+// failed placement because its parent's limit for its container type was
+// exceeded, mirroring the "unsupported container" status that
+// SetSupportedContainers/SupportsNoContainers already set for a
+// container type the parent no longer supports at all.
+func (m *Machine) SetContainerLimitExceeded(containerType instance.ContainerType, limit int) error {
+	return m.SetStatus(status.StatusError, "container limit exceeded", map[string]interface{}{
+		"type":  string(containerType),
+		"limit": limit,
+	})
+}
+
+// EnforceContainerLimit is the single call AddMachineInsideMachine
+// (machine.go) must make, with existingCount set to the number of
+// containers of containerType parent already hosts, before it finishes
+// placing container inside parent. Without this call,
+// CheckContainerLimit/SetContainerLimitExceeded are only ever computed
+// and stored, never acted on, so a parent's container limit is not
+// actually enforced.
+func (parent *Machine) EnforceContainerLimit(container *Machine, containerType instance.ContainerType, existingCount int) error {
+	limits, err := parent.SupportedContainerLimits()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := CheckContainerLimit(limits, containerType, existingCount); err != nil {
+		if setErr := container.SetContainerLimitExceeded(containerType, limits[containerType].MaxContainers); setErr != nil {
+			return errors.Trace(setErr)
+		}
+		return errors.Trace(err)
+	}
+	return nil
+}
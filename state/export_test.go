@@ -0,0 +1,18 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"gopkg.in/mgo.v2/txn"
+)
+
+// These let state_test black-box tests exercise topology.go's otherwise
+// unexported behaviour.
+var TopologyFingerprintOps = (*Machine).topologyFingerprintOps
+
+// RunMachineTxn runs ops against m's state, for tests that need to apply
+// the ops an unexported ops-builder like topologyFingerprintOps returns.
+func RunMachineTxn(m *Machine, ops []txn.Op) error {
+	return m.st.db().RunTransaction(ops)
+}
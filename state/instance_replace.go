@@ -0,0 +1,139 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/instance"
+)
+
+const instanceHistoryC = "instanceHistory"
+
+// ReplaceOptions controls how Machine.ReplaceInstance behaves.
+type ReplaceOptions struct {
+	// RequireDown, if true, fails the replacement unless the machine's
+	// agent is currently not present (the machine is "down"), so that a
+	// live agent is never yanked out from under itself.
+	RequireDown bool
+
+	// Reason is recorded in the machine's instance history, e.g.
+	// "spot-reclaim" or "in-place-migration".
+	Reason string
+
+	// ResetInstanceStatus, if true, clears the machine's instance
+	// status back to pending rather than carrying the old instance's
+	// status forward.
+	ResetInstanceStatus bool
+}
+
+// instanceHistoryDoc records one prior instance a machine was bound to,
+// so operators can audit replacements.
+type instanceHistoryDoc struct {
+	DocID      string      `bson:"_id"`
+	MachineId  string      `bson:"machineid"`
+	InstanceId instance.Id `bson:"instanceid"`
+	ReplacedAt time.Time   `bson:"replaced-at"`
+	Reason     string      `bson:"reason"`
+}
+
+// InstanceHistoryRecord is the result of Machine.InstanceHistory: a
+// prior instance id this machine was bound to, when it was replaced, and
+// why.
+type InstanceHistoryRecord struct {
+	InstanceId instance.Id
+	ReplacedAt time.Time
+	Reason     string
+}
+
+// ReplaceInstance atomically rebinds this machine's identity to a new
+// instance, preserving the machine's assigned units, storage attachments
+// and constraints. It is intended for spot-instance recovery or in-place
+// cloud migration, where the VM underneath a machine is destroyed and
+// recreated but Juju should keep treating it as the same machine.
+//
+// The old nonce is invalidated as part of the same transaction, so any
+// agent still running against the old instance is rejected the next time
+// it calls CheckProvisioned.
+func (m *Machine) ReplaceInstance(newInstanceId instance.Id, newNonce string, hwc *instance.HardwareCharacteristics, opts ReplaceOptions) error {
+	if opts.RequireDown {
+		agentPresent, err := m.AgentPresence()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if agentPresent {
+			return errors.Errorf("cannot replace instance for machine %s: agent is still present", m.Id())
+		}
+	}
+
+	oldInstanceId, err := m.InstanceId()
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	historyDoc := &instanceHistoryDoc{
+		DocID:      m.st.docID(m.globalKey() + "#" + string(oldInstanceId)),
+		MachineId:  m.Id(),
+		InstanceId: oldInstanceId,
+		ReplacedAt: time.Now(),
+		Reason:     opts.Reason,
+	}
+
+	set := bson.D{
+		{"instanceid", newInstanceId},
+		{"nonce", newNonce},
+	}
+	if hwc != nil {
+		set = append(set, bson.DocElem{Name: "hardwarecharacteristics", Value: hwc})
+	}
+	if opts.ResetInstanceStatus {
+		set = append(set, bson.DocElem{Name: "instancestatus", Value: statusDoc{Status: StatusPending}})
+	}
+
+	ops := []txn.Op{
+		{
+			C:      machinesC,
+			Id:     m.doc.DocID,
+			Assert: bson.D{{"life", Alive}, {"nonce", m.doc.Nonce}},
+			Update: bson.D{{"$set", set}},
+		},
+		{
+			C:      instanceHistoryC,
+			Id:     historyDoc.DocID,
+			Assert: txn.DocMissing,
+			Insert: historyDoc,
+		},
+	}
+	if err := m.st.db().RunTransaction(ops); err != nil {
+		return errors.Annotatef(err, "cannot replace instance for machine %s", m.Id())
+	}
+	return m.Refresh()
+}
+
+// InstanceHistory returns the instances this machine was previously
+// bound to, oldest first, so operators can audit replacements made via
+// ReplaceInstance.
+func (m *Machine) InstanceHistory() ([]InstanceHistoryRecord, error) {
+	coll, closer := m.st.db().GetCollection(instanceHistoryC)
+	defer closer()
+
+	var docs []instanceHistoryDoc
+	if err := coll.Find(bson.D{{"machineid", m.Id()}}).Sort("replaced-at").All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	records := make([]InstanceHistoryRecord, len(docs))
+	for i, doc := range docs {
+		records[i] = InstanceHistoryRecord{
+			InstanceId: doc.InstanceId,
+			ReplacedAt: doc.ReplacedAt,
+			Reason:     doc.Reason,
+		}
+	}
+	return records, nil
+}
@@ -5,6 +5,7 @@ package state_test
 
 import (
 	"sort"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
@@ -109,6 +110,45 @@ func (s *MachineSuite) TestSetUnsetRebootFlag(c *gc.C) {
 	c.Assert(rebootFlag, jc.IsFalse)
 }
 
+func (s *MachineSuite) TestRequestReboot(c *gc.C) {
+	pending, err := s.machine.PendingReboot()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.IsNil)
+
+	scheduledAt := time.Now().Add(time.Hour)
+	err = s.machine.RequestReboot(state.RebootRequest{
+		ScheduledAt: scheduledAt,
+		RequestedBy: "unit-mysql-0",
+		Reason:      "kernel-upgrade",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	pending, err = s.machine.PendingReboot()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.NotNil)
+	c.Assert(pending.ScheduledAt.Equal(scheduledAt), jc.IsTrue)
+	c.Assert(pending.RequestedBy, gc.Equals, "unit-mysql-0")
+	c.Assert(pending.Reason, gc.Equals, "kernel-upgrade")
+
+	err = s.machine.ClearReboot()
+	c.Assert(err, jc.ErrorIsNil)
+
+	pending, err = s.machine.PendingReboot()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.IsNil)
+}
+
+func (s *MachineSuite) TestRequestRebootDeadMachine(c *gc.C) {
+	err := s.machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.machine.RequestReboot(state.RebootRequest{Reason: "kernel-upgrade"})
+	c.Assert(err, gc.Equals, mgo.ErrNotFound)
+
+	err = s.machine.ClearReboot()
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *MachineSuite) TestAddMachineInsideMachineModelDying(c *gc.C) {
 	model, err := s.State.Model()
 	c.Assert(err, jc.ErrorIsNil)
@@ -183,6 +223,53 @@ func (s *MachineSuite) TestShouldShutdownOrReboot(c *gc.C) {
 	c.Assert(rAction, gc.Equals, state.ShouldShutdown)
 }
 
+func (s *MachineSuite) TestSetRebootReady(c *gc.C) {
+	ready, err := s.machine.IsRebootReady()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ready, jc.IsFalse)
+
+	err = s.machine.SetRebootReady(true)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ready, err = s.machine.IsRebootReady()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ready, jc.IsTrue)
+
+	err = s.machine.SetRebootReady(false)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ready, err = s.machine.IsRebootReady()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ready, jc.IsFalse)
+}
+
+func (s *MachineSuite) TestWaitForChildrenReadySucceedsOnceAllReady(c *gc.C) {
+	c1, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, s.machine.Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = c1.SetRebootReady(true)
+	c.Assert(err, jc.ErrorIsNil)
+
+	pending, err := s.machine.WaitForChildrenReady(time.Second)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.HasLen, 0)
+}
+
+func (s *MachineSuite) TestWaitForChildrenReadyTimesOut(c *gc.C) {
+	c1, err := s.State.AddMachineInsideMachine(state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}, s.machine.Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+
+	pending, err := s.machine.WaitForChildrenReady(time.Millisecond)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, jc.DeepEquals, []string{c1.Id()})
+}
+
 func (s *MachineSuite) TestContainerDefaults(c *gc.C) {
 	c.Assert(string(s.machine.ContainerType()), gc.Equals, "")
 	containers, err := s.machine.Containers()
@@ -1553,6 +1640,52 @@ func (s *MachineSuite) TestSetProviderAddressesWithContainers(c *gc.C) {
 	c.Assert(machine.Addresses(), jc.DeepEquals, expectedAddresses)
 }
 
+func (s *MachineSuite) TestAddressForSpace(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Create subnet bound to a space, and pick an address from it.
+	subnetInfo := state.SubnetInfo{
+		CIDR:              "192.168.1.0/24",
+		AllocatableIPLow:  "192.168.1.0",
+		AllocatableIPHigh: "192.168.1.10",
+		SpaceID:           "space-db",
+	}
+	subnet, err := s.State.AddSubnet(subnetInfo)
+	c.Assert(err, jc.ErrorIsNil)
+
+	ipAddr, err := subnet.PickNewAddress()
+	c.Assert(err, jc.ErrorIsNil)
+	err = ipAddr.SetState(state.AddressStateAllocated)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Create an LXC container inside the machine, as in
+	// TestSetProviderAddressesOnContainer, and give it an address from
+	// the space-bound subnet.
+	template := state.MachineTemplate{
+		Series: "quantal",
+		Jobs:   []state.MachineJob{state.JobHostUnits},
+	}
+	container, err := s.State.AddMachineInsideMachine(template, machine.Id(), instance.LXC)
+	c.Assert(err, jc.ErrorIsNil)
+
+	addresses := network.NewAddresses("127.0.0.1", ipAddr.Value())
+	err = container.SetProviderAddresses(addresses...)
+	c.Assert(err, jc.ErrorIsNil)
+	err = container.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+
+	addr, err := container.AddressForSpace("space-db")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr.Value, gc.Equals, ipAddr.Value())
+
+	// A space with no bound addresses falls through to the usual
+	// cloud-local scope match rather than failing outright.
+	addr, err = container.AddressForSpace("space-unbound")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(addr.Value, gc.Equals, "127.0.0.1")
+}
+
 func (s *MachineSuite) TestSetProviderAddressesOnContainer(c *gc.C) {
 	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -1755,10 +1888,35 @@ func (s *MachineSuite) TestSetProviderAddressesConcurrentChangeEqual(c *gc.C) {
 	err = machine.SetProviderAddresses(addr0, addr1)
 	c.Assert(err, jc.ErrorIsNil)
 
-	// Doc will be updated; concurrent changes are explicitly ignored.
+	// The concurrent hook already set the same address set, so the
+	// hash-join reconciler finds an empty delta and skips writing
+	// anything: the doc's txn-revno is untouched.
 	revno2, err := state.TxnRevno(s.State, "machines", machineDocID)
 	c.Assert(err, jc.ErrorIsNil)
-	c.Assert(revno2, jc.GreaterThan, revno1)
+	c.Assert(revno2, gc.Equals, revno1)
+	c.Assert(machine.Addresses(), jc.SameContents, []network.Address{addr0, addr1})
+}
+
+func (s *MachineSuite) TestSetProviderAddressesReorderedNoWrite(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	addr0 := network.NewAddress("127.0.0.1")
+	addr1 := network.NewAddress("8.8.8.8")
+
+	err = machine.SetProviderAddresses(addr0, addr1)
+	c.Assert(err, jc.ErrorIsNil)
+	machineDocID := state.DocID(s.State, machine.Id())
+	revno0, err := state.TxnRevno(s.State, "machines", machineDocID)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Setting the same addresses back in a different order is still the
+	// same set under the hash-join key, so it must not bump the revno.
+	err = machine.SetProviderAddresses(addr1, addr0)
+	c.Assert(err, jc.ErrorIsNil)
+	revno1, err := state.TxnRevno(s.State, "machines", machineDocID)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(revno1, gc.Equals, revno0)
 	c.Assert(machine.Addresses(), jc.SameContents, []network.Address{addr0, addr1})
 }
 
@@ -2320,6 +2478,22 @@ func (s *MachineSuite) TestSupportsNoContainersSetsAllToError(c *gc.C) {
 	}
 }
 
+func (s *MachineSuite) TestCheckContainerLimit(c *gc.C) {
+	limits := map[instance.ContainerType]state.ContainerLimits{
+		instance.LXC: {MaxContainers: 2},
+	}
+
+	err := state.CheckContainerLimit(limits, instance.LXC, 1)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = state.CheckContainerLimit(limits, instance.LXC, 2)
+	c.Assert(err, gc.ErrorMatches, "container limit exceeded")
+
+	// A type with no entry, or MaxContainers unset, is unbounded.
+	err = state.CheckContainerLimit(limits, instance.KVM, 1000)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *MachineSuite) TestMachineAgentTools(c *gc.C) {
 	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -2364,6 +2538,29 @@ func (s *MachineSuite) TestMachineValidActions(c *gc.C) {
 	}
 }
 
+func (s *MachineSuite) TestValidateMachineActionCustomRegistration(c *gc.C) {
+	err := state.RegisterMachineAction("ping-host", `{
+		"$schema": "http://json-schema.org/draft-04/schema#",
+		"title": "ping-host",
+		"type": "object",
+		"properties": {
+			"host": {"type": "string"}
+		},
+		"required": ["host"]
+	}`)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(state.RegisteredMachineActions(), jc.Contains, "ping-host")
+
+	err = state.ValidateMachineAction("ping-host", map[string]interface{}{"host": "10.0.0.1"})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = state.ValidateMachineAction("ping-host", map[string]interface{}{})
+	c.Assert(err.Error(), gc.Equals, `validation failed: (root) : "host" property is missing and required, given {}`)
+
+	err = state.ValidateMachineAction("not-registered", nil)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *MachineSuite) TestMachineAddDifferentAction(c *gc.C) {
 	m, err := s.State.AddMachine("trusty", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -0,0 +1,256 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/constraints"
+)
+
+// PlacementPolicy chooses which of a set of candidate machines a unit
+// should be assigned to. Implementations run under the same transaction
+// machinery as AssignToMachine, so Place must be side-effect free: it
+// only picks a candidate, it does not itself perform the assignment.
+type PlacementPolicy interface {
+	// Place returns the candidate machine this policy would assign u
+	// to. It returns an error if none of candidates is suitable.
+	Place(st *State, u *Unit, candidates []*Machine) (*Machine, error)
+}
+
+var (
+	placementPoliciesMu sync.Mutex
+	placementPolicies   = make(map[string]PlacementPolicy)
+)
+
+// RegisterPlacementPolicy registers a named placement policy so it can
+// be selected by name from model config or Unit.Place. It panics on a
+// duplicate name, matching the pattern used by secrets/provider and
+// other Juju registries populated from init functions.
+func RegisterPlacementPolicy(name string, p PlacementPolicy) {
+	placementPoliciesMu.Lock()
+	defer placementPoliciesMu.Unlock()
+	if _, dup := placementPolicies[name]; dup {
+		panic("state: RegisterPlacementPolicy called twice for name " + name)
+	}
+	placementPolicies[name] = p
+}
+
+// placementPolicy looks up a registered policy by name.
+func placementPolicy(name string) (PlacementPolicy, error) {
+	placementPoliciesMu.Lock()
+	defer placementPoliciesMu.Unlock()
+	p, ok := placementPolicies[name]
+	if !ok {
+		return nil, errors.NotFoundf("placement policy %q", name)
+	}
+	return p, nil
+}
+
+func init() {
+	RegisterPlacementPolicy("spread-by-az", spreadByAZPolicy{})
+	RegisterPlacementPolicy("pack-by-constraints", packByConstraintsPolicy{})
+	RegisterPlacementPolicy("anti-affinity", antiAffinityPolicy{})
+}
+
+// maxPlaceAttempts bounds how many times Place re-runs the placement
+// policy against freshly refreshed candidates after a losing race against
+// a concurrent change to one of them, before giving up.
+const maxPlaceAttempts = 3
+
+// Place runs the named placement policy over candidates and assigns u to
+// whichever machine it picks, under the same transaction machinery as
+// AssignToMachine. If policyName is empty, the model's default placement
+// policy (ModelConfig "default-placement-policy") is used.
+//
+// Selecting a candidate and assigning u to it are not one atomic
+// operation - the candidate set can change in between, e.g. another unit
+// landing on the chosen machine between Place and AssignToMachine. To
+// keep that window from producing a stale assignment, a losing race
+// (AssignToMachine failing because the machine's state moved under it)
+// is retried against freshly reloaded candidates rather than surfaced
+// straight to the caller.
+func (u *Unit) Place(policyName string, candidates []*Machine) error {
+	if policyName == "" {
+		cfg, err := u.st.ModelConfig()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		policyName, _ = cfg.AllAttrs()["default-placement-policy"].(string)
+	}
+	if policyName == "" {
+		return errors.New("no placement policy specified and no model default configured")
+	}
+
+	policy, err := placementPolicy(policyName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxPlaceAttempts; attempt++ {
+		fresh, err := refreshMachines(candidates)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		m, err := policy.Place(u.st, u, fresh)
+		if err != nil {
+			return errors.Annotatef(err, "applying %q placement policy", policyName)
+		}
+		lastErr = u.AssignToMachine(m)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return errors.Annotatef(lastErr, "assigning unit after %d placement attempts", maxPlaceAttempts)
+}
+
+// refreshMachines reloads each of machines from state, so a PlacementPolicy
+// re-run after a failed assignment sees each candidate's current state
+// rather than the snapshot from the previous attempt.
+func refreshMachines(machines []*Machine) ([]*Machine, error) {
+	fresh := make([]*Machine, len(machines))
+	for i, m := range machines {
+		refreshed, err := m.st.Machine(m.Id())
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		fresh[i] = refreshed
+	}
+	return fresh, nil
+}
+
+// spreadByAZPolicy assigns to the candidate whose availability zone
+// currently hosts the fewest units, to spread load across AZs.
+type spreadByAZPolicy struct{}
+
+// Place is part of the PlacementPolicy interface.
+func (spreadByAZPolicy) Place(st *State, u *Unit, candidates []*Machine) (*Machine, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidate machines")
+	}
+
+	azCount := make(map[string]int)
+	for _, m := range candidates {
+		az, err := m.AvailabilityZone()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		units, err := m.Units()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		azCount[az] += len(units)
+	}
+
+	best := candidates[0]
+	bestAZ, err := best.AvailabilityZone()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	bestCount := azCount[bestAZ]
+	for _, m := range candidates[1:] {
+		az, err := m.AvailabilityZone()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if azCount[az] < bestCount {
+			best, bestCount = m, azCount[az]
+		}
+	}
+	return best, nil
+}
+
+// packByConstraintsPolicy assigns to the candidate whose constraints
+// match the unit's application constraints most closely, packing units
+// onto already-similar machines rather than spreading them.
+type packByConstraintsPolicy struct{}
+
+// Place is part of the PlacementPolicy interface.
+func (packByConstraintsPolicy) Place(st *State, u *Unit, candidates []*Machine) (*Machine, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidate machines")
+	}
+
+	app, err := u.Application()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	wanted, err := app.Constraints()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	type scored struct {
+		m     *Machine
+		score int
+	}
+	scoredCandidates := make([]scored, len(candidates))
+	for i, m := range candidates {
+		have, err := m.Constraints()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		scoredCandidates[i] = scored{m: m, score: constraintsSimilarity(wanted, have)}
+	}
+	sort.SliceStable(scoredCandidates, func(i, j int) bool {
+		return scoredCandidates[i].score > scoredCandidates[j].score
+	})
+	return scoredCandidates[0].m, nil
+}
+
+// constraintsSimilarity is a small heuristic: one point for each
+// comparable constraint field (arch, instance type, cores, mem) that
+// matches exactly between wanted and have.
+func constraintsSimilarity(wanted, have constraints.Value) int {
+	score := 0
+	if wanted.Arch != nil && have.Arch != nil && *wanted.Arch == *have.Arch {
+		score++
+	}
+	if wanted.InstanceType != nil && have.InstanceType != nil && *wanted.InstanceType == *have.InstanceType {
+		score++
+	}
+	if wanted.CpuCores != nil && have.CpuCores != nil && *wanted.CpuCores == *have.CpuCores {
+		score++
+	}
+	if wanted.Mem != nil && have.Mem != nil && *wanted.Mem == *have.Mem {
+		score++
+	}
+	return score
+}
+
+// antiAffinityPolicy assigns to the candidate hosting the fewest units
+// of the unit's own application, so that a single machine failure is
+// less likely to take out every unit of a service.
+type antiAffinityPolicy struct{}
+
+// Place is part of the PlacementPolicy interface.
+func (antiAffinityPolicy) Place(st *State, u *Unit, candidates []*Machine) (*Machine, error) {
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidate machines")
+	}
+
+	appName := u.ApplicationName()
+	best := candidates[0]
+	bestCount := -1
+	for _, m := range candidates {
+		units, err := m.Units()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		count := 0
+		for _, other := range units {
+			if other.ApplicationName() == appName {
+				count++
+			}
+		}
+		if bestCount == -1 || count < bestCount {
+			best, bestCount = m, count
+		}
+	}
+	return best, nil
+}
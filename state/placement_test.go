@@ -0,0 +1,100 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type PlacementSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&PlacementSuite{})
+
+func (s *PlacementSuite) TestRegisterPlacementPolicyPanicsOnDuplicate(c *gc.C) {
+	c.Assert(func() {
+		state.RegisterPlacementPolicy("anti-affinity", nil)
+	}, gc.PanicMatches, "state: RegisterPlacementPolicy called twice for name anti-affinity")
+}
+
+func (s *PlacementSuite) TestPlaceNoPolicyNoDefault(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = unit.Place("", nil)
+	c.Assert(err, gc.ErrorMatches, "no placement policy specified and no model default configured")
+}
+
+func (s *PlacementSuite) TestPlaceUnknownPolicy(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = unit.Place("no-such-policy", nil)
+	c.Assert(err, gc.ErrorMatches, `placement policy "no-such-policy" not found`)
+}
+
+func (s *PlacementSuite) TestPlaceAntiAffinityNoCandidates(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = unit.Place("anti-affinity", nil)
+	c.Assert(err, gc.ErrorMatches, `applying "anti-affinity" placement policy: no candidate machines`)
+}
+
+func (s *PlacementSuite) TestPlaceAntiAffinityPrefersLeastLoadedMachine(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+
+	crowded, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	quiet, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	first, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = first.AssignToMachine(crowded)
+	c.Assert(err, jc.ErrorIsNil)
+
+	second, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = second.Place("anti-affinity", []*state.Machine{crowded, quiet})
+	c.Assert(err, jc.ErrorIsNil)
+
+	machineID, err := second.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machineID, gc.Equals, quiet.Id())
+}
+
+func (s *PlacementSuite) TestPlaceRefreshesStaleCandidateSnapshot(c *gc.C) {
+	svc := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+
+	// soon-to-be-crowded and spare are the *Machine values Place gets
+	// called with; they are snapshotted before the filler unit lands on
+	// soon-to-be-crowded, so a naive Place trusting this snapshot would
+	// see both machines as equally loaded and could pick either one.
+	soonToBeCrowded, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	spare, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	filler, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = filler.AssignToMachine(soonToBeCrowded)
+	c.Assert(err, jc.ErrorIsNil)
+
+	unit, err := svc.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.Place("anti-affinity", []*state.Machine{soonToBeCrowded, spare})
+	c.Assert(err, jc.ErrorIsNil)
+
+	machineID, err := unit.AssignedMachineId()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machineID, gc.Equals, spare.Id())
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// ShouldWait extends RebootAction (see machine.go) for the coordinated
+// case: an ancestor machine has requested a reboot, but one or more of
+// this machine's descendants have not yet reported reboot-ready, so the
+// agent should defer rather than reboot or shut down immediately.
+const ShouldWait RebootAction = "wait"
+
+const rebootReadyC = "rebootReady"
+
+// rebootReadyDoc records whether a single machine has finished draining
+// units and flushing volumes in preparation for a reboot requested by an
+// ancestor machine.
+type rebootReadyDoc struct {
+	DocID     string `bson:"_id"`
+	MachineId string `bson:"machineid"`
+	Ready     bool   `bson:"ready"`
+}
+
+// SetRebootReady records whether this machine has finished preparing for
+// a reboot requested by an ancestor machine. Containers call this once
+// their units are drained and volumes flushed, so the host does not race
+// a hard reboot against in-flight work.
+func (m *Machine) SetRebootReady(ready bool) error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt == 0 {
+			return []txn.Op{{
+				C:      rebootReadyC,
+				Id:     m.globalKey(),
+				Assert: txn.DocMissing,
+				Insert: &rebootReadyDoc{DocID: m.globalKey(), MachineId: m.Id(), Ready: ready},
+			}}, nil
+		}
+		return []txn.Op{{
+			C:      rebootReadyC,
+			Id:     m.globalKey(),
+			Assert: txn.DocExists,
+			Update: bson.D{{"$set", bson.D{{"ready", ready}}}},
+		}}, nil
+	}
+	return m.st.db().Run(buildTxn)
+}
+
+// IsRebootReady reports whether this machine has most recently called
+// SetRebootReady(true).
+func (m *Machine) IsRebootReady() (bool, error) {
+	coll, closer := m.st.db().GetCollection(rebootReadyC)
+	defer closer()
+
+	var doc rebootReadyDoc
+	err := coll.FindId(m.globalKey()).One(&doc)
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return doc.Ready, nil
+}
+
+// WaitForChildrenReady blocks until every descendant of this machine has
+// called SetRebootReady(true), or until timeout elapses, whichever comes
+// first. It returns the ids of descendants still not ready when it gave
+// up, which is empty on success.
+func (m *Machine) WaitForChildrenReady(timeout time.Duration) ([]string, error) {
+	deadline := time.Now().Add(timeout)
+	const pollInterval = 500 * time.Millisecond
+
+	for {
+		pending, err := m.pendingDescendants()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		if time.Now().After(deadline) {
+			return pending, nil
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// CoordinatedRebootAction adjusts base - the action ShouldRebootOrShutdown
+// (machine.go) would otherwise return from its own reboot-request/flag
+// state - to ShouldWait if this machine has descendants that have not
+// yet called SetRebootReady(true). ShouldRebootOrShutdown must call this
+// before returning ShouldReboot or ShouldShutdown, otherwise a container
+// can be torn down by its host mid-drain.
+func (m *Machine) CoordinatedRebootAction(base RebootAction) (RebootAction, error) {
+	if base != ShouldReboot && base != ShouldShutdown {
+		return base, nil
+	}
+	pending, err := m.pendingDescendants()
+	if err != nil {
+		return base, errors.Trace(err)
+	}
+	if len(pending) > 0 {
+		return ShouldWait, nil
+	}
+	return base, nil
+}
+
+// pendingDescendants returns the ids of this machine's containers,
+// recursively, that have not yet called SetRebootReady(true).
+func (m *Machine) pendingDescendants() ([]string, error) {
+	containers, err := m.Containers()
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	var pending []string
+	for _, id := range containers {
+		child, err := m.st.Machine(id)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		ready, err := child.IsRebootReady()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if !ready {
+			pending = append(pending, id)
+		}
+		grandchildren, err := child.pendingDescendants()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		pending = append(pending, grandchildren...)
+	}
+	return pending, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+const rebootRequestC = "rebootRequest"
+
+// RebootRequest describes a scheduled reboot: who asked for it, when it
+// should take effect, and why.
+type RebootRequest struct {
+	// ScheduledAt is when the reboot should take effect. The zero value
+	// means "as soon as possible", matching the semantics of the old
+	// boolean SetRebootFlag(true).
+	ScheduledAt time.Time
+
+	// RequestedBy is the tag of the entity that asked for the reboot,
+	// e.g. a unit running the juju-reboot hook tool, or a controller
+	// worker performing maintenance.
+	RequestedBy string
+
+	// Reason is a short, human-readable explanation, e.g.
+	// "kernel-upgrade" or "charm-request".
+	Reason string
+}
+
+// rebootRequestDoc is the persistent form of a RebootRequest, keyed by
+// the requesting machine's global key.
+type rebootRequestDoc struct {
+	DocID       string    `bson:"_id"`
+	MachineId   string    `bson:"machineid"`
+	ScheduledAt time.Time `bson:"scheduled-at"`
+	RequestedBy string    `bson:"requested-by"`
+	Reason      string    `bson:"reason"`
+}
+
+// RequestReboot records a scheduled reboot request for this machine,
+// replacing any previously pending one. ShouldRebootOrShutdown consults
+// RebootRequest.ScheduledAt so agents can plan a maintenance window
+// instead of rebooting the instant the request is made.
+func (m *Machine) RequestReboot(req RebootRequest) error {
+	if m.Life() != Alive {
+		return mgo.ErrNotFound
+	}
+
+	doc := &rebootRequestDoc{
+		DocID:       m.globalKey(),
+		MachineId:   m.Id(),
+		ScheduledAt: req.ScheduledAt,
+		RequestedBy: req.RequestedBy,
+		Reason:      req.Reason,
+	}
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		if attempt > 0 {
+			if err := m.Refresh(); err != nil {
+				return nil, err
+			}
+			if m.Life() != Alive {
+				return nil, mgo.ErrNotFound
+			}
+		}
+
+		existing, err := m.PendingReboot()
+		if err != nil {
+			return nil, err
+		}
+		op := txn.Op{C: rebootRequestC, Id: m.globalKey()}
+		if existing == nil {
+			op.Assert = txn.DocMissing
+			op.Insert = doc
+		} else {
+			op.Assert = txn.DocExists
+			op.Update = bson.D{{"$set", bson.D{
+				{"scheduled-at", doc.ScheduledAt},
+				{"requested-by", doc.RequestedBy},
+				{"reason", doc.Reason},
+			}}}
+		}
+		return []txn.Op{op}, nil
+	}
+	return m.st.db().Run(buildTxn)
+}
+
+// PendingReboot returns the currently scheduled reboot request for this
+// machine, or nil if none is pending.
+func (m *Machine) PendingReboot() (*RebootRequest, error) {
+	coll, closer := m.st.db().GetCollection(rebootRequestC)
+	defer closer()
+
+	var doc rebootRequestDoc
+	err := coll.FindId(m.globalKey()).One(&doc)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &RebootRequest{
+		ScheduledAt: doc.ScheduledAt,
+		RequestedBy: doc.RequestedBy,
+		Reason:      doc.Reason,
+	}, nil
+}
+
+// ClearReboot cancels any pending reboot request for this machine. It
+// succeeds whether or not a request was actually pending, and on a dead
+// machine, so that agents can always clean up on the way out.
+func (m *Machine) ClearReboot() error {
+	ops := []txn.Op{{
+		C:      rebootRequestC,
+		Id:     m.globalKey(),
+		Remove: true,
+	}}
+	if err := m.st.db().RunTransaction(ops); err != nil && err != txn.ErrAborted {
+		return errors.Annotatef(err, "cannot clear reboot request for machine %s", m.Id())
+	}
+	return nil
+}
+
+// SetRebootFlag is retained for backwards compatibility with agents and
+// tests written against the old boolean API. Passing true requests an
+// immediate reboot; passing false clears any pending request. New code
+// should call RequestReboot/PendingReboot directly so it can record who
+// asked and why, and so it can schedule rather than always requesting
+// "now".
+func (m *Machine) SetRebootFlag(flag bool) error {
+	if !flag {
+		return m.ClearReboot()
+	}
+	return m.RequestReboot(RebootRequest{ScheduledAt: time.Now()})
+}
+
+// GetRebootFlag is retained for backwards compatibility; it reports
+// whether a reboot is currently pending for this machine, regardless of
+// its scheduled time.
+func (m *Machine) GetRebootFlag() (bool, error) {
+	req, err := m.PendingReboot()
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return req != nil, nil
+}
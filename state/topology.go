@@ -0,0 +1,264 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+
+	"github.com/juju/juju/instance"
+)
+
+const networkInterfacesC = "networkInterfaces"
+const topologyC = "topology"
+
+// NetworkInterfaceInfo describes one network interface attached to a
+// machine's instance, as reported by the provider at provisioning time.
+type NetworkInterfaceInfo struct {
+	// Name is the interface's device name, e.g. "eth0".
+	Name string
+
+	// MACAddress is the interface's hardware address.
+	MACAddress string
+
+	// Address is the interface's primary IP address.
+	Address string
+
+	// CIDR is the subnet the address belongs to.
+	CIDR string
+
+	// VLANTag is the 802.1Q tag, or zero if the interface is untagged.
+	VLANTag int
+
+	// MTU is the interface's maximum transmission unit.
+	MTU int
+
+	// GatewayAddress is the default route out of this interface, if
+	// any.
+	GatewayAddress string
+
+	// DNSServers lists the nameservers this interface was configured
+	// with.
+	DNSServers []string
+
+	// ProviderId is the provider's id for this interface.
+	ProviderId string
+
+	// ParentDevice names the bond or bridge this interface is a member
+	// of, empty if it is not part of one.
+	ParentDevice string
+
+	// SpaceId is the space this interface's subnet belongs to. It is
+	// resolved from CIDR against the Subnet collection at ingest time;
+	// an interface whose CIDR matches no known subnet fails the whole
+	// SetInstanceInfo call rather than being silently dropped.
+	SpaceId string
+}
+
+// networkInterfaceDoc is the persistent form of a NetworkInterfaceInfo.
+type networkInterfaceDoc struct {
+	DocID        string   `bson:"_id"`
+	MachineId    string   `bson:"machineid"`
+	Name         string   `bson:"name"`
+	MACAddress   string   `bson:"macaddress"`
+	Address      string   `bson:"address"`
+	CIDR         string   `bson:"cidr"`
+	VLANTag      int      `bson:"vlantag"`
+	MTU          int      `bson:"mtu"`
+	Gateway      string   `bson:"gateway"`
+	DNSServers   []string `bson:"dnsservers"`
+	ProviderId   string   `bson:"providerid"`
+	ParentDevice string   `bson:"parentdevice"`
+	SpaceId      string   `bson:"spaceid"`
+}
+
+// topologyDoc is the persistent form of a machine's TopologyFingerprint.
+// It lives in its own collection, keyed by the machine's globalKey,
+// rather than as a field on the machine doc itself - the same pattern
+// address_history.go, instance_replace.go and reboot.go use for
+// per-machine facts that change independently of the rest of the machine
+// doc, so that computing and storing a fingerprint never needs a
+// machineDoc schema change.
+type topologyDoc struct {
+	DocID       string `bson:"_id"`
+	MachineId   string `bson:"machineid"`
+	Fingerprint string `bson:"fingerprint"`
+}
+
+// topologyFingerprintOps validates nics against known subnets and
+// returns the txn.Ops needed to persist them plus the machine's computed
+// TopologyFingerprint. SetInstanceInfo (machine.go) must call this and
+// append the returned ops to its own transaction, the same way it already
+// composes in other per-provisioning ops builders - TopologyFingerprint
+// is never populated for a provisioned machine otherwise. An unresolvable
+// NIC causes the whole call to fail (and, by virtue of running in that
+// same transaction, leaves the machine unprovisioned) rather than
+// silently dropping the interface.
+func (m *Machine) topologyFingerprintOps(hwc *instance.HardwareCharacteristics, nics []NetworkInterfaceInfo, volumeIds []string, az string) ([]txn.Op, string, error) {
+	ops := make([]txn.Op, 0, len(nics)+1)
+	for _, nic := range nics {
+		if nic.SpaceId == "" {
+			subnet, err := m.st.Subnet(nic.CIDR)
+			if err != nil {
+				return nil, "", errors.Annotatef(err, "resolving space for NIC %q (%s)", nic.Name, nic.CIDR)
+			}
+			nic.SpaceId = subnet.SpaceID()
+		}
+		doc := &networkInterfaceDoc{
+			DocID:        m.st.docID(m.globalKey() + "#" + nic.Name),
+			MachineId:    m.Id(),
+			Name:         nic.Name,
+			MACAddress:   nic.MACAddress,
+			Address:      nic.Address,
+			CIDR:         nic.CIDR,
+			VLANTag:      nic.VLANTag,
+			MTU:          nic.MTU,
+			Gateway:      nic.GatewayAddress,
+			DNSServers:   nic.DNSServers,
+			ProviderId:   nic.ProviderId,
+			ParentDevice: nic.ParentDevice,
+			SpaceId:      nic.SpaceId,
+		}
+		ops = append(ops, txn.Op{
+			C:      networkInterfacesC,
+			Id:     doc.DocID,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		})
+	}
+
+	fingerprint := computeTopologyFingerprint(hwc, nics, volumeIds, az)
+	doc := &topologyDoc{
+		DocID:       m.globalKey(),
+		MachineId:   m.Id(),
+		Fingerprint: fingerprint,
+	}
+	fingerprintOp, err := m.upsertTopologyDocOp(doc, fingerprint)
+	if err != nil {
+		return nil, "", errors.Trace(err)
+	}
+	ops = append(ops, fingerprintOp)
+	return ops, fingerprint, nil
+}
+
+// upsertTopologyDocOp returns the single txn.Op needed to bring this
+// machine's topologyDoc to fingerprint, inserting it on first use and
+// updating it thereafter. It looks up which case applies before building
+// the op rather than including both an insert and an update op in the
+// same transaction, since mgo/txn rejects two ops against the same
+// (collection, id) pair - see the fix to addressReconcileOps for the bug
+// that pattern caused elsewhere in this package.
+func (m *Machine) upsertTopologyDocOp(doc *topologyDoc, fingerprint string) (txn.Op, error) {
+	coll, closer := m.st.db().GetCollection(topologyC)
+	defer closer()
+
+	err := coll.FindId(doc.DocID).One(&topologyDoc{})
+	if err == mgo.ErrNotFound {
+		return txn.Op{
+			C:      topologyC,
+			Id:     doc.DocID,
+			Assert: txn.DocMissing,
+			Insert: doc,
+		}, nil
+	}
+	if err != nil {
+		return txn.Op{}, errors.Trace(err)
+	}
+	return txn.Op{
+		C:      topologyC,
+		Id:     doc.DocID,
+		Assert: txn.DocExists,
+		Update: bson.D{{"$set", bson.D{{"fingerprint", fingerprint}}}},
+	}, nil
+}
+
+// computeTopologyFingerprint hashes the parts of a machine's shape that
+// matter to higher-level workers (hardware characteristics, volumes,
+// NICs, availability zone) so they can cheaply detect a change without
+// diffing every subdocument.
+func computeTopologyFingerprint(hwc *instance.HardwareCharacteristics, nics []NetworkInterfaceInfo, volumeIds []string, az string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "az=%s\n", az)
+	if hwc != nil {
+		fmt.Fprintf(h, "hwc=%s\n", hwc.String())
+	}
+
+	sortedVolumes := append([]string(nil), volumeIds...)
+	sort.Strings(sortedVolumes)
+	for _, v := range sortedVolumes {
+		fmt.Fprintf(h, "volume=%s\n", v)
+	}
+
+	sortedNICs := append([]NetworkInterfaceInfo(nil), nics...)
+	sort.Slice(sortedNICs, func(i, j int) bool { return sortedNICs[i].Name < sortedNICs[j].Name })
+	for _, nic := range sortedNICs {
+		fmt.Fprintf(h, "nic=%s,%s,%s,%d\n", nic.Name, nic.MACAddress, nic.Address, nic.VLANTag)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NetworkInterfaces returns the network interfaces most recently
+// recorded for this machine's instance by SetInstanceInfo.
+func (m *Machine) NetworkInterfaces() ([]NetworkInterfaceInfo, error) {
+	coll, closer := m.st.db().GetCollection(networkInterfacesC)
+	defer closer()
+
+	var docs []networkInterfaceDoc
+	if err := coll.Find(bson.D{{"machineid", m.Id()}}).All(&docs); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	nics := make([]NetworkInterfaceInfo, len(docs))
+	for i, doc := range docs {
+		nics[i] = NetworkInterfaceInfo{
+			Name:           doc.Name,
+			MACAddress:     doc.MACAddress,
+			Address:        doc.Address,
+			CIDR:           doc.CIDR,
+			VLANTag:        doc.VLANTag,
+			MTU:            doc.MTU,
+			GatewayAddress: doc.Gateway,
+			DNSServers:     doc.DNSServers,
+			ProviderId:     doc.ProviderId,
+			ParentDevice:   doc.ParentDevice,
+			SpaceId:        doc.SpaceId,
+		}
+	}
+	return nics, nil
+}
+
+// TopologyFingerprint returns the machine's current topology
+// fingerprint, as last computed by SetInstanceInfo. It returns the empty
+// string if SetInstanceInfo has never run a NIC/topology update for this
+// machine (e.g. a machine provisioned before this feature existed).
+func (m *Machine) TopologyFingerprint() (string, error) {
+	coll, closer := m.st.db().GetCollection(topologyC)
+	defer closer()
+
+	var doc topologyDoc
+	err := coll.FindId(m.globalKey()).One(&doc)
+	if err == mgo.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return doc.Fingerprint, nil
+}
+
+// WatchTopology returns a NotifyWatcher that fires whenever this
+// machine's TopologyFingerprint changes, letting workers detect a
+// change in hardware, volumes, NICs or availability zone without
+// diffing every subdocument themselves.
+func (m *Machine) WatchTopology() NotifyWatcher {
+	return newNotifyCollWatcher(m.st, topologyC, m.globalKey())
+}
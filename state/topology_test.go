@@ -0,0 +1,68 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+)
+
+type TopologySuite struct {
+	ConnSuite
+	machine *state.Machine
+}
+
+var _ = gc.Suite(&TopologySuite{})
+
+func (s *TopologySuite) SetUpTest(c *gc.C) {
+	s.ConnSuite.SetUpTest(c)
+	var err error
+	s.machine, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *TopologySuite) TestTopologyFingerprintOpsUnresolvableSubnet(c *gc.C) {
+	nics := []state.NetworkInterfaceInfo{{Name: "eth0", CIDR: "10.99.99.0/24"}}
+
+	ops, fingerprint, err := state.TopologyFingerprintOps(s.machine, nil, nics, nil, "az1")
+	c.Assert(err, gc.ErrorMatches, `resolving space for NIC "eth0" \(10\.99\.99\.0/24\): .*`)
+	c.Assert(ops, gc.IsNil)
+	c.Assert(fingerprint, gc.Equals, "")
+}
+
+func (s *TopologySuite) TestTopologyFingerprintOpsInsertThenUpdate(c *gc.C) {
+	ops, fp1, err := state.TopologyFingerprintOps(s.machine, nil, nil, nil, "az1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ops, gc.HasLen, 1)
+
+	err = state.RunMachineTxn(s.machine, ops)
+	c.Assert(err, jc.ErrorIsNil)
+
+	got, err := s.machine.TopologyFingerprint()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got, gc.Equals, fp1)
+
+	// A second call against the same machine must produce an op that
+	// updates the topologyDoc upsertTopologyDocOp already inserted,
+	// rather than trying (and failing) to insert it again.
+	ops2, fp2, err := state.TopologyFingerprintOps(s.machine, nil, nil, nil, "az2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(ops2, gc.HasLen, 1)
+
+	err = state.RunMachineTxn(s.machine, ops2)
+	c.Assert(err, jc.ErrorIsNil)
+
+	got2, err := s.machine.TopologyFingerprint()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(got2, gc.Equals, fp2)
+	c.Assert(got2, gc.Not(gc.Equals), got)
+}
+
+func (s *TopologySuite) TestTopologyFingerprintUnset(c *gc.C) {
+	fingerprint, err := s.machine.TopologyFingerprint()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(fingerprint, gc.Equals, "")
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package caascredential
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/worker/v2"
+	"github.com/juju/worker/v2/dependency"
+	"gopkg.in/juju/names.v2"
+
+	jujuclock "github.com/juju/clock"
+)
+
+// ManifoldConfig describes the resources and configuration
+// the worker Manifold starts needs, as wired into the machine agent's
+// dependency engine.
+type ManifoldConfig struct {
+	// ClockName is the name of the clock manifold.
+	ClockName string
+
+	// NewCredentialResolver and NewCredentialUpdater build the worker's
+	// collaborators, deferred behind functions so the manifold doesn't
+	// need to depend on the api/caas or cmd/juju/caas packages directly.
+	NewCredentialResolver func() (CredentialResolver, error)
+	NewCredentialUpdater  func() (CredentialUpdater, error)
+
+	// CloudCredentialTag identifies which credential this manifold's
+	// worker refreshes.
+	CloudCredentialTag names.CloudCredentialTag
+
+	// KubeconfigPath is the on-disk kubeconfig to re-read on each tick.
+	KubeconfigPath string
+
+	// RefreshInterval is the "caas-credential-refresh-interval" model
+	// config value, resolved by the manifold's caller.
+	RefreshInterval func() int64
+}
+
+// Validate returns an error if the config is missing a required field.
+func (config ManifoldConfig) Validate() error {
+	if config.ClockName == "" {
+		return errors.NotValidf("empty ClockName")
+	}
+	if config.NewCredentialResolver == nil {
+		return errors.NotValidf("nil NewCredentialResolver")
+	}
+	if config.NewCredentialUpdater == nil {
+		return errors.NotValidf("nil NewCredentialUpdater")
+	}
+	if config.CloudCredentialTag.Id() == "" {
+		return errors.NotValidf("empty CloudCredentialTag")
+	}
+	if config.KubeconfigPath == "" {
+		return errors.NotValidf("empty KubeconfigPath")
+	}
+	return nil
+}
+
+// Manifold returns a dependency.Manifold that runs the CAAS credential
+// refresh worker, for registration alongside the machine/unit agent's
+// other manifolds.
+func Manifold(config ManifoldConfig) dependency.Manifold {
+	return dependency.Manifold{
+		Inputs: []string{config.ClockName},
+		Start: func(context dependency.Context) (worker.Worker, error) {
+			if err := config.Validate(); err != nil {
+				return nil, errors.Trace(err)
+			}
+
+			var clk jujuclock.Clock
+			if err := context.Get(config.ClockName, &clk); err != nil {
+				return nil, errors.Trace(err)
+			}
+			resolver, err := config.NewCredentialResolver()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			updater, err := config.NewCredentialUpdater()
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+
+			interval := DefaultRefreshInterval
+			if seconds := config.RefreshInterval(); seconds > 0 {
+				interval = time.Duration(seconds) * time.Second
+			}
+
+			return NewWorker(Config{
+				CloudCredentialTag: config.CloudCredentialTag,
+				KubeconfigPath:     config.KubeconfigPath,
+				Resolver:           resolver,
+				Updater:            updater,
+				Clock:              clk,
+				Interval:           interval,
+			})
+		},
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+// Package caascredential runs a worker that periodically re-resolves a
+// CAAS cloud's kubeconfig-derived credential and pushes the refreshed
+// value to the controller, so short-lived tokens (EKS's 15 minutes,
+// GKE's 1 hour) don't expire out from under a long-running model. An
+// OIDC id-token is re-read from the kubeconfig as it stands; it is not
+// refreshed. It is the background counterpart to the
+// credential resolution `juju add-k8s` and `juju update-k8s-credential`
+// do on demand (see cmd/juju/caas).
+package caascredential
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/loggo"
+	"github.com/juju/worker/v2"
+	"github.com/juju/worker/v2/catacomb"
+	"gopkg.in/juju/names.v2"
+
+	jujuclock "github.com/juju/clock"
+	jujucloud "github.com/juju/juju/cloud"
+)
+
+var logger = loggo.GetLogger("juju.worker.caascredential")
+
+// DefaultRefreshInterval is used when the model config does not set
+// "caas-credential-refresh-interval".
+const DefaultRefreshInterval = 10 * time.Minute
+
+// minBackoff and maxBackoff bound how long the worker waits after a
+// failed refresh before retrying, doubling on each consecutive failure.
+const (
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// CredentialResolver re-derives a cloud.Credential from the on-disk
+// kubeconfig at path, the same resolution add-k8s performs inline
+// (exec plugin, OIDC id-token pass-through, or cloud CLI token mint).
+type CredentialResolver interface {
+	ResolveFromKubeconfig(path string) (jujucloud.Credential, error)
+}
+
+// CredentialUpdater pushes a refreshed credential to the controller.
+type CredentialUpdater interface {
+	UpdateCredential(tag string, credential jujucloud.Credential) error
+}
+
+// Config holds the information the worker needs to periodically refresh
+// one CAAS cloud's credential.
+type Config struct {
+	// CloudCredentialTag identifies which credential to refresh.
+	CloudCredentialTag names.CloudCredentialTag
+
+	// KubeconfigPath is the on-disk kubeconfig add-k8s originally read,
+	// re-read on every tick in case it was rotated in place.
+	KubeconfigPath string
+
+	// Resolver re-derives the credential from KubeconfigPath.
+	Resolver CredentialResolver
+
+	// Updater pushes the refreshed credential to the controller.
+	Updater CredentialUpdater
+
+	// Clock is used for the refresh ticker and backoff, so tests can
+	// use a mock clock instead of real time.
+	Clock jujuclock.Clock
+
+	// Interval is how often to refresh, sourced from the model config
+	// knob "caas-credential-refresh-interval" (DefaultRefreshInterval if
+	// unset).
+	Interval time.Duration
+}
+
+// Validate returns an error if config is missing a required field.
+func (config Config) Validate() error {
+	if config.CloudCredentialTag.Id() == "" {
+		return errors.NotValidf("empty CloudCredentialTag")
+	}
+	if config.KubeconfigPath == "" {
+		return errors.NotValidf("empty KubeconfigPath")
+	}
+	if config.Resolver == nil {
+		return errors.NotValidf("nil Resolver")
+	}
+	if config.Updater == nil {
+		return errors.NotValidf("nil Updater")
+	}
+	if config.Clock == nil {
+		return errors.NotValidf("nil Clock")
+	}
+	if config.Interval <= 0 {
+		return errors.NotValidf("non-positive Interval")
+	}
+	return nil
+}
+
+// caasCredentialWorker periodically re-resolves and pushes a CAAS cloud's
+// credential, backing off on failure and reporting its state for
+// introspection.
+type caasCredentialWorker struct {
+	catacomb catacomb.Catacomb
+	config   Config
+
+	reportMu    sync.Mutex
+	lastSync    time.Time
+	lastError   string
+	consecutive int
+}
+
+// NewWorker returns a worker that refreshes config.CloudCredentialTag's
+// credential every config.Interval, until killed.
+func NewWorker(config Config) (worker.Worker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	w := &caasCredentialWorker{config: config}
+	err := catacomb.Invoke(catacomb.Plan{
+		Site: &w.catacomb,
+		Work: w.loop,
+	})
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return w, nil
+}
+
+// Kill is part of the worker.Worker interface.
+func (w *caasCredentialWorker) Kill() {
+	w.catacomb.Kill(nil)
+}
+
+// Wait is part of the worker.Worker interface.
+func (w *caasCredentialWorker) Wait() error {
+	return w.catacomb.Wait()
+}
+
+// Report is part of the worker.Reporter interface, surfacing the last
+// sync outcome for `juju engine-report`-style introspection.
+func (w *caasCredentialWorker) Report() map[string]interface{} {
+	w.reportMu.Lock()
+	defer w.reportMu.Unlock()
+	report := map[string]interface{}{
+		"credential":           w.config.CloudCredentialTag.String(),
+		"consecutive-failures": w.consecutive,
+	}
+	if !w.lastSync.IsZero() {
+		report["last-sync"] = w.lastSync
+	}
+	if w.lastError != "" {
+		report["last-error"] = w.lastError
+	}
+	return report
+}
+
+func (w *caasCredentialWorker) loop() error {
+	clk := w.config.Clock
+	timer := clk.NewTimer(w.config.Interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.catacomb.Dying():
+			return w.catacomb.ErrDying()
+		case <-timer.Chan():
+		}
+
+		wait := w.config.Interval
+		if err := w.refresh(); err != nil {
+			w.recordFailure(err)
+			wait = backoffFor(w.consecutiveFailures())
+			logger.Warningf("refreshing credential %q failed, retrying in %s: %v",
+				w.config.CloudCredentialTag, wait, err)
+		} else {
+			w.recordSuccess(clk.Now())
+			logger.Debugf("refreshed credential %q", w.config.CloudCredentialTag)
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (w *caasCredentialWorker) refresh() error {
+	cred, err := w.config.Resolver.ResolveFromKubeconfig(w.config.KubeconfigPath)
+	if err != nil {
+		return errors.Annotate(err, "resolving credential from kubeconfig")
+	}
+	if err := w.config.Updater.UpdateCredential(w.config.CloudCredentialTag.String(), cred); err != nil {
+		return errors.Annotate(err, "pushing refreshed credential")
+	}
+	return nil
+}
+
+func (w *caasCredentialWorker) recordSuccess(now time.Time) {
+	w.reportMu.Lock()
+	defer w.reportMu.Unlock()
+	w.lastSync = now
+	w.lastError = ""
+	w.consecutive = 0
+}
+
+func (w *caasCredentialWorker) recordFailure(err error) {
+	w.reportMu.Lock()
+	defer w.reportMu.Unlock()
+	w.lastError = err.Error()
+	w.consecutive++
+}
+
+func (w *caasCredentialWorker) consecutiveFailures() int {
+	w.reportMu.Lock()
+	defer w.reportMu.Unlock()
+	return w.consecutive
+}
+
+// backoffFor returns how long to wait before the next attempt after
+// consecutive failed refreshes, doubling from minBackoff up to
+// maxBackoff.
+func backoffFor(consecutive int) time.Duration {
+	wait := minBackoff
+	for i := 1; i < consecutive; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
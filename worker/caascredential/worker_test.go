@@ -0,0 +1,70 @@
+// Copyright 2023 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package caascredential_test
+
+import (
+	"time"
+
+	jujuclock "github.com/juju/clock"
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+	"gopkg.in/juju/names.v2"
+
+	jujucloud "github.com/juju/juju/cloud"
+	"github.com/juju/juju/worker/caascredential"
+)
+
+type ConfigSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&ConfigSuite{})
+
+func validConfig() caascredential.Config {
+	return caascredential.Config{
+		CloudCredentialTag: names.NewCloudCredentialTag("k8s/bob/default"),
+		KubeconfigPath:     "/home/bob/.kube/config",
+		Resolver:           stubResolver{},
+		Updater:            stubUpdater{},
+		Clock:              jujuclock.WallClock,
+		Interval:           time.Minute,
+	}
+}
+
+func (s *ConfigSuite) TestValidate(c *gc.C) {
+	c.Assert(validConfig().Validate(), jc.ErrorIsNil)
+}
+
+func (s *ConfigSuite) TestValidateMissingFields(c *gc.C) {
+	tests := []struct {
+		mutate func(*caascredential.Config)
+	}{
+		{func(cfg *caascredential.Config) { cfg.CloudCredentialTag = names.CloudCredentialTag{} }},
+		{func(cfg *caascredential.Config) { cfg.KubeconfigPath = "" }},
+		{func(cfg *caascredential.Config) { cfg.Resolver = nil }},
+		{func(cfg *caascredential.Config) { cfg.Updater = nil }},
+		{func(cfg *caascredential.Config) { cfg.Clock = nil }},
+		{func(cfg *caascredential.Config) { cfg.Interval = 0 }},
+	}
+	for i, t := range tests {
+		c.Logf("test %d", i)
+		cfg := validConfig()
+		t.mutate(&cfg)
+		c.Assert(cfg.Validate(), jc.Satisfies, errors.IsNotValid)
+	}
+}
+
+type stubResolver struct{}
+
+func (stubResolver) ResolveFromKubeconfig(path string) (jujucloud.Credential, error) {
+	return jujucloud.Credential{}, nil
+}
+
+type stubUpdater struct{}
+
+func (stubUpdater) UpdateCredential(tag string, credential jujucloud.Credential) error {
+	return nil
+}